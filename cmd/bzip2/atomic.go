@@ -0,0 +1,37 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// An output file is written under a temporary name next to its final
+// one and renamed into place only once complete, so an interrupted or
+// failed run never leaves a truncated file under the final name, nor
+// destroys the file an overwrite would have replaced. Package fileio
+// names it, in the same directory, so the rename never crosses
+// filesystems, not even when -C points to another one
+
+// closeOutput flushes w, as returned by createOutput for f, and closes
+// f, reporting errors the deferred Close would lose. Standard output
+// is only flushed. If the original is to be removed, the data is
+// synced to disk first
+func closeOutput(f *os.File, w io.Writer) error {
+	if err := flushOutput(w); err != nil {
+		return err
+	}
+	if f == os.Stdout {
+		return nil
+	}
+	if !*keep {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
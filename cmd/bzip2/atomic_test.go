@@ -0,0 +1,89 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// dirNames returns the sorted names of the entries of dir
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+// writeFile writes data as name under dir
+func writeFile(t *testing.T, dir, name string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// The output appears under its name once complete, and nothing else
+// is left next to it
+func TestAtomicOutput(t *testing.T) {
+	dir := t.TempDir()
+	data := binaryData()
+	writeFile(t, dir, "a", data)
+	if _, stderr, status := runBzip2(t, dir, nil, "a"); status != exitOK {
+		t.Fatalf("bzip2 a: status %d: %s", status, stderr)
+	}
+	if got := dirNames(t, dir); !reflect.DeepEqual(got, []string{"a.bz2"}) {
+		t.Errorf("after bzip2 a: %q, want [a.bz2]", got)
+	}
+	if _, stderr, status := runBzip2(t, dir, nil, "-d", "a.bz2"); status != exitOK {
+		t.Fatalf("bzip2 -d a.bz2: status %d: %s", status, stderr)
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "a")); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("bzip2 -d a.bz2 gave %d bytes, %v, want %d", len(got), err, len(data))
+	}
+}
+
+// A failed decompression leaves neither a truncated output nor its
+// temporary file, and the file -f would have replaced stays as it was
+func TestAtomicOutputFailure(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a", binaryData())
+	if _, stderr, status := runBzip2(t, dir, nil, "a"); status != exitOK {
+		t.Fatalf("bzip2 a: status %d: %s", status, stderr)
+	}
+	z, err := os.ReadFile(filepath.Join(dir, "a.bz2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	z[len(z)/2] ^= 0x10
+	writeFile(t, dir, "a.bz2", z)
+
+	if _, _, status := runBzip2(t, dir, nil, "-d", "a.bz2"); status == exitOK {
+		t.Errorf("bzip2 -d of corrupt data succeeded")
+	}
+	if got := dirNames(t, dir); !reflect.DeepEqual(got, []string{"a.bz2"}) {
+		t.Errorf("after a failed bzip2 -d: %q, want [a.bz2]", got)
+	}
+
+	writeFile(t, dir, "a", []byte("kept\n"))
+	if _, _, status := runBzip2(t, dir, nil, "-df", "a.bz2"); status == exitOK {
+		t.Errorf("bzip2 -df of corrupt data succeeded")
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "a")); err != nil || string(got) != "kept\n" {
+		t.Errorf("a failed bzip2 -df replaced a with %q, %v", got, err)
+	}
+	if got := dirNames(t, dir); !reflect.DeepEqual(got, []string{"a", "a.bz2"}) {
+		t.Errorf("after a failed bzip2 -df: %q, want [a a.bz2]", got)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// backupValue is --backup[=method], which renames originals instead of
+// removing them. The methods are those of GNU cp and mv: simple
+// backups are named name~, numbered ones name.~N~, and existing (the
+// default) makes numbered backups of files that already have some
+type backupValue string
+
+var backup backupValue
+
+func (b *backupValue) String() string { return string(*b) }
+
+func (b *backupValue) Set(s string) error {
+	switch s {
+	case "true", "existing", "nil":
+		*b = "existing"
+	case "simple", "never":
+		*b = "simple"
+	case "numbered", "t":
+		*b = "numbered"
+	case "false", "none", "off":
+		*b = ""
+	default:
+		return fmt.Errorf("unknown backup method %q: simple, numbered or existing expected", s)
+	}
+	return nil
+}
+
+func (b *backupValue) IsBoolFlag() bool { return true }
+
+// backupName returns the name the original name is backed up as
+func backupName(name string) (string, error) {
+	last, err := lastBackup(name)
+	if err != nil {
+		return "", err
+	}
+	if backup == "numbered" || (backup == "existing" && last > 0) {
+		return fmt.Sprintf("%s.~%d~", name, last+1), nil
+	}
+	return name + "~", nil
+}
+
+// lastBackup returns the highest N of the numbered backups of name,
+// or 0 if there are none
+func lastBackup(name string) (int, error) {
+	entries, err := os.ReadDir(filepath.Dir(name))
+	if err != nil {
+		return 0, err
+	}
+	prefix := filepath.Base(name) + ".~"
+	last := 0
+	for _, e := range entries {
+		s := e.Name()
+		if !strings.HasPrefix(s, prefix) || !strings.HasSuffix(s, "~") {
+			continue
+		}
+		n, err := strconv.Atoi(s[len(prefix) : len(s)-1])
+		if err == nil && n > last {
+			last = n
+		}
+	}
+	return last, nil
+}
+
+// removeOriginal removes the original name, or backs it up with
+// --backup
+func removeOriginal(name string) error {
+	if backup == "" {
+		return os.Remove(name)
+	}
+	dst, err := backupName(name)
+	if err != nil {
+		return err
+	}
+	return os.Rename(name, dst)
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBackupName(t *testing.T) {
+	defer func(b backupValue) { backup = b }(backup)
+	tests := []struct {
+		method   string
+		existing []string
+		want     string
+	}{
+		{"simple", nil, "a~"},
+		{"simple", []string{"a.~3~"}, "a~"},
+		{"numbered", nil, "a.~1~"},
+		{"numbered", []string{"a.~1~", "a.~10~", "a.~x~", "ab.~20~"}, "a.~11~"},
+		{"existing", nil, "a~"},
+		{"existing", []string{"a~"}, "a~"},
+		{"existing", []string{"a.~2~"}, "a.~3~"},
+		{"t", nil, "a.~1~"},
+		{"never", []string{"a.~2~"}, "a~"},
+		{"nil", []string{"a.~2~"}, "a.~3~"},
+	}
+	for _, tt := range tests {
+		if err := backup.Set(tt.method); err != nil {
+			t.Fatal(err)
+		}
+		dir := t.TempDir()
+		for _, name := range tt.existing {
+			writeFile(t, dir, name, nil)
+		}
+		got, err := backupName(filepath.Join(dir, "a"))
+		if err != nil || got != filepath.Join(dir, tt.want) {
+			t.Errorf("--backup=%s with %q: %q, %v, want %q", tt.method, tt.existing, got, err, tt.want)
+		}
+	}
+	if err := backup.Set("sometimes"); err == nil {
+		t.Errorf("--backup=sometimes accepted")
+	}
+}
+
+// Originals are kept under their backup names, one more each run
+func TestBackupOriginals(t *testing.T) {
+	dir := t.TempDir()
+	for i, args := range [][]string{
+		{"--backup=numbered", "-f", "a"},
+		{"--backup", "-f", "a"},
+		{"--backup=simple", "-f", "a"},
+	} {
+		writeFile(t, dir, "a", []byte{byte(i)})
+		if _, stderr, status := runBzip2(t, dir, nil, args...); status != exitOK {
+			t.Fatalf("bzip2 %q: status %d: %s", args, status, stderr)
+		}
+	}
+	if got, want := dirNames(t, dir), []string{"a.bz2", "a.~1~", "a.~2~", "a~"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("left %q, want %q", got, want)
+	}
+	for name, b := range map[string]byte{"a.~1~": 0, "a.~2~": 1, "a~": 2} {
+		if data, err := os.ReadFile(filepath.Join(dir, name)); err != nil || len(data) != 1 || data[0] != b {
+			t.Errorf("%s holds %q, %v, want %q", name, data, err, []byte{b})
+		}
+	}
+}
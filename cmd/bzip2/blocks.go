@@ -0,0 +1,38 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"io"
+
+	"github.com/pedroalbanese/bzip2/pkg/bz"
+)
+
+// Blocks are found and decoded in parallel by package bz (see its
+// blocks.go), with the workers, memory limit and tracing of the
+// command line given here
+
+// errNotSplittable means a file has to be decoded serially
+var errNotSplittable = bz.ErrNotSplittable
+
+// engineOptions returns the options package bz runs with, using up to
+// workers goroutines at level
+func engineOptions(level, workers int) *bz.Options {
+	return &bz.Options{Level: level, Workers: workers, Memory: memLimit, Trace: tracef}
+}
+
+// decodeParallel decompresses the size-byte file f into w, decoding
+// its blocks concurrently with up to workers goroutines. It returns
+// errNotSplittable, before writing anything, if the file's structure
+// doesn't allow it
+func decodeParallel(f io.ReaderAt, size int64, w io.Writer, workers int) (int64, error) {
+	return bz.DecompressAt(w, f, size, engineOptions(*level, workers))
+}
+
+// decodeBlocks decompresses f, whose layout l is, into w, see
+// decodeParallel
+func decodeBlocks(f io.ReaderAt, l *bz.Layout, w io.Writer, workers int) (int64, error) {
+	return l.Decode(w, f, engineOptions(*level, workers))
+}
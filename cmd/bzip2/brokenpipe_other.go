@@ -0,0 +1,20 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build !plan9
+// +build !plan9
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isBrokenPipe reports whether err comes from writing to a pipe whose
+// reader is gone
+func isBrokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE)
+}
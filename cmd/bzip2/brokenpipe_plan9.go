@@ -0,0 +1,15 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package main
+
+import "strings"
+
+// isBrokenPipe reports whether err comes from writing to a pipe whose
+// reader is gone, which Plan 9 reports as a hungup channel rather
+// than EPIPE
+func isBrokenPipe(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "hungup channel")
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// A file named twice, or reachable from two of the FILEs given, would
+// be processed twice at once, both runs racing on the same output.
+// Each file is claimed before processing, so that it is done once;
+// only output to stdout, as in bzcat file file, may repeat it
+
+// fileKey identifies a file by device and inode
+type fileKey struct {
+	dev, ino uint64
+}
+
+var claimed struct {
+	sync.Mutex
+	ids   map[fileKey]bool
+	paths map[string]bool // where there are no inodes
+}
+
+// claim reports whether the file name, described by fi, is seen for
+// the first time, and claims it
+func claim(name string, fi os.FileInfo) bool {
+	claimed.Lock()
+	defer claimed.Unlock()
+	if id, ok := fileID(fi); ok {
+		if claimed.ids == nil {
+			claimed.ids = make(map[fileKey]bool)
+		}
+		if claimed.ids[id] {
+			return false
+		}
+		claimed.ids[id] = true
+		return true
+	}
+	if abs, err := filepath.Abs(name); err == nil {
+		name = abs
+	}
+	if claimed.paths == nil {
+		claimed.paths = make(map[string]bool)
+	}
+	if claimed.paths[name] {
+		return false
+	}
+	claimed.paths[name] = true
+	return true
+}
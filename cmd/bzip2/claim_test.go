@@ -0,0 +1,51 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// A file given twice, or reached from two FILEs, is done once, and
+// the second time is no error
+func TestClaimOnce(t *testing.T) {
+	dir := t.TempDir()
+	d := filepath.Join(dir, "d")
+	if err := os.Mkdir(d, 0777); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, d, "a", binaryData())
+	if err := os.Link(filepath.Join(d, "a"), filepath.Join(d, "b")); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"-k", "--cores", "2", "d/a", "d/a", "./d/a"},
+		{"-kr", "--cores", "2", "d", "d", "d/a"},
+	} {
+		os.Remove(filepath.Join(d, "a.bz2"))
+		os.Remove(filepath.Join(d, "b.bz2"))
+		if _, stderr, status := runBzip2(t, dir, nil, args...); status != exitOK || len(stderr) > 0 {
+			t.Errorf("bzip2 %q: status %d: %s", args, status, stderr)
+		}
+		names := dirNames(t, d)
+		if !reflect.DeepEqual(names, []string{"a", "a.bz2", "b"}) && !reflect.DeepEqual(names, []string{"a", "b", "b.bz2"}) {
+			t.Errorf("bzip2 %q left %q, want one of a and b compressed", args, names)
+		}
+	}
+
+	// To stdout, as in bzcat, a file may repeat
+	z, _, status := runBzip2(t, dir, nil, "-c", "d/a", "d/a")
+	if status != exitOK {
+		t.Fatalf("bzip2 -c d/a d/a: status %d", status)
+	}
+	got, _, _ := runBzip2(t, dir, z, "-dc")
+	if want := append(binaryData(), binaryData()...); !bytes.Equal(got, want) {
+		t.Errorf("bzip2 -c d/a d/a holds %d bytes, want %d", len(got), len(want))
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// compressedSuffixes are completed as operands after -d or -t
+var compressedSuffixes = []string{"bz2", "tbz2", "tbz", "bz"}
+
+// completionNames are the program names completions are installed for
+var completionNames = []string{"bzip2", "bunzip2", "bzcat"}
+
+// option is a flag as seen by the completion scripts
+type option struct {
+	short, long string // either may be empty
+	desc        string
+	arg         string // argument name, empty for boolean flags
+}
+
+// options lists every flag with its long alias, if any, in the order
+// of the usage message
+func options() []option {
+	long := make(map[string]string)
+	for i := 0; i < len(aliases); i += 2 {
+		long[aliases[i]] = aliases[i+1]
+	}
+	var opts []option
+	flag.VisitAll(func(f *flag.Flag) {
+		arg, desc := flag.UnquoteUsage(f)
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			arg = ""
+		}
+		o := option{desc: desc, arg: arg}
+		if len(f.Name) == 1 {
+			o.short, o.long = f.Name, long[f.Name]
+		} else {
+			o.long = f.Name
+		}
+		opts = append(opts, o)
+	})
+	sort.SliceStable(opts, func(i, j int) bool {
+		return strings.ToLower(opts[i].short+opts[i].long) < strings.ToLower(opts[j].short+opts[j].long)
+	})
+	return opts
+}
+
+// writeCompletion writes the completion script for shell to w
+func writeCompletion(w io.Writer, shell string) error {
+	switch shell {
+	case "bash":
+		bashCompletion(w, options())
+	case "zsh":
+		zshCompletion(w, options())
+	case "fish":
+		fishCompletion(w, options())
+	default:
+		return fmt.Errorf("unknown shell %q: bash, zsh or fish expected", shell)
+	}
+	return nil
+}
+
+func bashCompletion(w io.Writer, opts []option) {
+	var words, withArg, fileArg, dirArg []string
+	for _, o := range opts {
+		var names []string
+		if o.short != "" {
+			names = append(names, "-"+o.short)
+		}
+		if o.long != "" {
+			names = append(names, "--"+o.long)
+		}
+		words = append(words, names...)
+		switch o.arg {
+		case "":
+		case "file":
+			fileArg = append(fileArg, names...)
+		case "dir":
+			dirArg = append(dirArg, names...)
+		default:
+			withArg = append(withArg, names...)
+		}
+	}
+	fmt.Fprintf(w, `# bash completion for %[1]s
+_bzip2() {
+	local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}
+	case $prev in
+	%[2]s)
+		COMPREPLY=($(compgen -f -- "$cur"))
+		return ;;
+	%[3]s)
+		COMPREPLY=($(compgen -d -- "$cur"))
+		return ;;
+	%[4]s)
+		return ;;
+	esac
+	if [[ $cur == -* ]]; then
+		COMPREPLY=($(compgen -W '%[5]s' -- "$cur"))
+		return
+	fi
+	local w decompress=
+	[[ ${COMP_WORDS[0]##*/} == @(bunzip2|bzcat) ]] && decompress=1
+	for w in "${COMP_WORDS[@]:1:COMP_CWORD-1}"; do
+		case $w in
+		--) break ;;
+		--decompress|--test|-[dt]*|-[!-]*[dt]*) decompress=1 ;;
+		esac
+	done
+	if [[ -z $decompress ]]; then
+		COMPREPLY=($(compgen -f -- "$cur"))
+		return
+	fi
+	COMPREPLY=($(compgen -d -- "$cur"))
+	local s
+	for s in %[6]s; do
+		COMPREPLY+=($(compgen -f -X "!*.$s" -- "$cur"))
+	done
+}
+complete -o filenames -F _bzip2 %[1]s
+`, strings.Join(completionNames, " "), strings.Join(fileArg, "|"),
+		strings.Join(dirArg, "|"), strings.Join(withArg, "|"),
+		strings.Join(words, " "), strings.Join(compressedSuffixes, " "))
+}
+
+func zshCompletion(w io.Writer, opts []option) {
+	esc := strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`, ":", `\:`)
+	fmt.Fprintf(w, "#compdef %s\n\n_bzip2() {\n\tlocal -a args\n\targs=(\n",
+		strings.Join(completionNames, " "))
+	for _, o := range opts {
+		action := ""
+		switch o.arg {
+		case "":
+		case "file":
+			action = ":file:_files"
+		case "dir":
+			action = ":dir:_files -/"
+		default:
+			action = ":" + esc.Replace(o.arg) + ": "
+		}
+		var specs []string
+		if o.short != "" {
+			suffix := ""
+			if action != "" {
+				suffix = "+"
+			}
+			specs = append(specs, "-"+o.short+suffix)
+		}
+		if o.long != "" {
+			suffix := ""
+			if action != "" {
+				suffix = "="
+			}
+			specs = append(specs, "--"+o.long+suffix)
+		}
+		for _, spec := range specs {
+			fmt.Fprintf(w, "\t\t'%s[%s]%s'\n", spec, esc.Replace(o.desc), action)
+		}
+	}
+	fmt.Fprintf(w, `		'*: :->files'
+	)
+	_arguments -s $args && return
+	if [[ $service == (bunzip2|bzcat) ]] || (( $words[(I)(--decompress|--test|-[^-]#[dt]*)] )); then
+		_files -g '*.(%s)(-.)'
+	else
+		_files
+	fi
+}
+
+_bzip2 "$@"
+`, strings.Join(compressedSuffixes, "|"))
+}
+
+func fishCompletion(w io.Writer, opts []option) {
+	esc := strings.NewReplacer(`\`, `\\`, "'", `\'`)
+	fmt.Fprintln(w, "# fish completion for", strings.Join(completionNames, " "))
+	for _, name := range completionNames {
+		for _, o := range opts {
+			fmt.Fprintf(w, "complete -c %s", name)
+			if o.short != "" {
+				fmt.Fprintf(w, " -s %s", o.short)
+			}
+			if o.long != "" {
+				fmt.Fprintf(w, " -l %s", o.long)
+			}
+			switch o.arg {
+			case "":
+			case "file", "dir":
+				fmt.Fprint(w, " -r -F")
+			default:
+				fmt.Fprint(w, " -x")
+			}
+			fmt.Fprintf(w, " -d '%s'\n", esc.Replace(o.desc))
+		}
+		cond := "__fish_contains_opt -s d decompress -s t test"
+		if name != "bzip2" {
+			cond = "true"
+		}
+		var suffixes []string
+		for _, s := range compressedSuffixes {
+			suffixes = append(suffixes, "(__fish_complete_suffix ."+s+")")
+		}
+		fmt.Fprintf(w, "complete -c %s -n '%s' -k -x -a '%s'\n",
+			name, cond, strings.Join(suffixes, " "))
+	}
+}
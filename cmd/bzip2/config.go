@@ -0,0 +1,72 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"rsc.io/getopt"
+)
+
+// Configuration files hold default options, one per line, named like
+// the flags (short or long) and followed by "= value" unless boolean:
+//
+//	# site policy
+//	level = 6
+//	keep
+//
+// The system-wide file is read first, then the user's, then $BZIP2,
+// $BZIP and the command line, each overriding the previous ones
+
+// systemConfig is the system-wide configuration file
+const systemConfig = "/etc/bzip2.conf"
+
+// configFiles returns the configuration files in the order they are read
+func configFiles() []string {
+	files := []string{systemConfig}
+	if dir, err := os.UserConfigDir(); err == nil {
+		files = append(files, filepath.Join(dir, "bzip2", "config"))
+	}
+	return files
+}
+
+// loadConfig applies the options in the configuration file name; a
+// file that doesn't exist is fine
+func loadConfig(name string) error {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	lines, err := splitList(f, '\n')
+	if err != nil {
+		return err
+	}
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value := line, "true"
+		if eq := strings.IndexByte(line, '='); eq >= 0 {
+			key = strings.TrimSpace(line[:eq])
+			value = strings.TrimSpace(line[eq+1:])
+		}
+		fl := getopt.CommandLine.Lookup(key)
+		if fl == nil {
+			return fmt.Errorf("%s:%d: unknown option %q", name, i+1, key)
+		}
+		if err := fl.Value.Set(value); err != nil {
+			return fmt.Errorf("%s:%d: invalid value %q for %s: %v", name, i+1, value, key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// availableCPUs returns the number of CPUs the process is allowed to use,
+// which is the affinity mask reported by runtime.NumCPU further limited
+// by the cgroup CPU quota, if any
+func availableCPUs() int {
+	n := runtime.NumCPU()
+	if q := cgroupCPUQuota(); q > 0 && q < n {
+		n = q
+	}
+	return n
+}
+
+// cgroupCPUQuota returns the CPU quota of the current cgroup rounded up
+// to whole CPUs, or 0 if there is no quota or it can't be determined
+func cgroupCPUQuota() int {
+	v1, v2 := cgroupPaths()
+
+	// cgroup v2: "<quota> <period>" or "max <period>"
+	if v2 != "" {
+		for _, dir := range []string{filepath.Join("/sys/fs/cgroup", v2), "/sys/fs/cgroup"} {
+			data, err := os.ReadFile(filepath.Join(dir, "cpu.max"))
+			if err != nil {
+				continue
+			}
+			fields := strings.Fields(string(data))
+			if len(fields) != 2 || fields[0] == "max" {
+				return 0
+			}
+			return quotaToCPUs(fields[0], fields[1])
+		}
+	}
+
+	// cgroup v1: separate cfs_quota_us and cfs_period_us files
+	if v1 != "" {
+		for _, dir := range []string{filepath.Join("/sys/fs/cgroup/cpu", v1), "/sys/fs/cgroup/cpu"} {
+			quota, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_quota_us"))
+			if err != nil {
+				continue
+			}
+			period, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_period_us"))
+			if err != nil {
+				continue
+			}
+			return quotaToCPUs(strings.TrimSpace(string(quota)),
+				strings.TrimSpace(string(period)))
+		}
+	}
+	return 0
+}
+
+// cgroupPaths returns the cgroup v1 "cpu" controller path and the
+// cgroup v2 unified path of the current process, as found in
+// /proc/self/cgroup
+func cgroupPaths() (v1, v2 string) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// Lines look like "hierarchy-ID:controller-list:cgroup-path"
+		parts := strings.SplitN(s.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			v2 = parts[2]
+			continue
+		}
+		for _, c := range strings.Split(parts[1], ",") {
+			if c == "cpu" {
+				v1 = parts[2]
+			}
+		}
+	}
+	return v1, v2
+}
+
+// quotaToCPUs converts a CFS quota/period pair into a CPU count, rounding
+// up so a quota of 1.5 CPUs still gets two workers
+func quotaToCPUs(quota, period string) int {
+	q, err := strconv.ParseInt(quota, 10, 64)
+	if err != nil || q <= 0 {
+		return 0
+	}
+	p, err := strconv.ParseInt(period, 10, 64)
+	if err != nil || p <= 0 {
+		return 0
+	}
+	return int((q + p - 1) / p)
+}
@@ -0,0 +1,16 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package main
+
+import "runtime"
+
+// availableCPUs returns the number of CPUs the process is allowed to use
+func availableCPUs() int {
+	return runtime.NumCPU()
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// bzip2 diff and bzip2 cmp compare the contents of two files, either
+// of which may be compressed, with diff or cmp: the first is
+// decompressed to a temporary file and the second streamed through a
+// pipe, as bzdiff does. Block sizes and the compressor used don't
+// matter, only the data. Given a single FILE, it is compared with its
+// name without the suffix. The exit status is that of diff or cmp: 0
+// if the contents are the same, 1 if they differ, 2 on errors
+
+// diffArgOpts and cmpArgOpts are the options of diff and of cmp whose
+// argument may be the next word; -i and -n are flags of diff
+var (
+	diffArgOpts = map[string]bool{
+		"-C": true, "-D": true, "-F": true, "-I": true, "-L": true,
+		"-S": true, "-U": true, "-W": true, "-x": true, "-X": true,
+		"--label": true, "--ignore-matching-lines": true, "--show-function-line": true,
+		"--starting-file": true, "--exclude": true, "--exclude-from": true,
+		"--width": true,
+	}
+	cmpArgOpts = map[string]bool{
+		"-i": true, "-n": true, "--ignore-initial": true, "--bytes": true,
+	}
+)
+
+func runDiff(args []string) int { return compareWith("diff", args, diffArgOpts) }
+
+func runCmp(args []string) int { return compareWith("cmp", args, cmpArgOpts) }
+
+// compareWith runs the comparison program prog, whose options taking
+// an argument are withArg, on the two FILEs in args, decompressed as
+// needed
+func compareWith(prog string, args []string, withArg map[string]bool) int {
+	opts, files, err := splitOptions(args, withArg)
+	if err == nil && len(files) == 1 {
+		if from, to := matchSuffix(files[0]); from != "" {
+			files = append(files, strings.TrimSuffix(files[0], from)+to)
+		} else {
+			err = errors.New(files[0] + ": no suffix to strip, give the other FILE")
+		}
+	}
+	if err == nil && len(files) != 2 {
+		err = errors.New("two FILEs expected")
+	}
+	if err != nil {
+		log.Printf("%s %s: %v", os.Args[0], prog, err)
+		return 2
+	}
+
+	a, b := files[0], files[1]
+	if compressed(a) {
+		tmp, err := decompressTemp(a)
+		if err != nil {
+			log.Printf("%s: %v", a, err)
+			return 2
+		}
+		defer os.Remove(tmp)
+		if prog == "diff" {
+			opts = append(opts, "--label", a)
+		}
+		a = tmp
+	}
+	pipeB := b != "-" && compressed(b)
+	if pipeB && prog == "diff" {
+		opts = append(opts, "--label", b)
+	}
+	args = append(opts, a, b)
+	if pipeB {
+		args[len(args)-1] = "-"
+	}
+
+	cmd := exec.Command(prog, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	var werr error
+	if pipeB {
+		in, err := cmd.StdinPipe()
+		if err != nil {
+			log.Printf("%s %s: %v", os.Args[0], prog, err)
+			return 2
+		}
+		if err := cmd.Start(); err != nil {
+			log.Printf("%s %s: %v", os.Args[0], prog, err)
+			return 2
+		}
+		// cmp stops reading at the first difference
+		werr = streamTo(in, b)
+		in.Close()
+		if isBrokenPipe(werr) || errors.Is(werr, os.ErrClosed) {
+			werr = nil
+		}
+	} else if err := cmd.Start(); err != nil {
+		log.Printf("%s %s: %v", os.Args[0], prog, err)
+		return 2
+	}
+	err = cmd.Wait()
+	if werr != nil {
+		log.Printf("%s: %v", b, werr)
+		return 2
+	}
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return exit.ExitCode()
+	}
+	if err != nil {
+		log.Printf("%s %s: %v", os.Args[0], prog, err)
+		return 2
+	}
+	return 0
+}
+
+// compressed reports whether name starts like a bzip2 stream
+func compressed(name string) bool {
+	f, err := os.Open(name)
+	if err != nil {
+		return false // the comparison reports the error
+	}
+	defer f.Close()
+	hdr := make([]byte, 4)
+	if _, err := io.ReadFull(f, hdr); err != nil {
+		return false
+	}
+	return bytes.HasPrefix(hdr, streamMagic) && hdr[3] >= '1' && hdr[3] <= '9'
+}
+
+// decompressTemp decompresses name into a new temporary file and
+// returns its name
+func decompressTemp(name string) (string, error) {
+	// Named after the original for cmp, which has no --label
+	f, err := os.CreateTemp("", filepath.Base(name)+".")
+	if err != nil {
+		return "", err
+	}
+	err = streamTo(f, name)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
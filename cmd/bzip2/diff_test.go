@@ -0,0 +1,58 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/pedroalbanese/bzip2/pkg/bz"
+)
+
+// writeCompressed writes data compressed as name under dir
+func writeCompressed(t *testing.T, dir, name string, data []byte) {
+	t.Helper()
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, _, err := bz.Compress(f, bytes.NewReader(data), nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// diff and cmp get their own options: -i is a flag of diff, while
+// for cmp it and -n take an argument
+func TestCompareOptions(t *testing.T) {
+	dir := t.TempDir()
+	writeCompressed(t, dir, "a.bz2", []byte("Hello, world\n"))
+	writeCompressed(t, dir, "b.bz2", []byte("hello, World\n"))
+	tests := []struct {
+		args   []string
+		status int
+	}{
+		{[]string{"diff", "a.bz2", "b.bz2"}, 1},
+		{[]string{"diff", "-i", "a.bz2", "b.bz2"}, 0},
+		{[]string{"diff", "-q", "-i", "a.bz2", "b.bz2"}, 0},
+		{[]string{"cmp", "-s", "a.bz2", "b.bz2"}, 1},
+		{[]string{"cmp", "-s", "-i", "1", "a.bz2", "b.bz2"}, 1},
+		{[]string{"cmp", "-s", "-n", "1", "a.bz2", "a.bz2"}, 0},
+		{[]string{"cmp", "-s", "-i", "8", "-n", "4", "a.bz2", "b.bz2"}, 0},
+	}
+	for _, tt := range tests {
+		if _, err := exec.LookPath(tt.args[0]); err != nil {
+			t.Logf("%s not found, skipped", tt.args[0])
+			continue
+		}
+		_, stderr, status := runBzip2(t, dir, nil, tt.args...)
+		if status != tt.status {
+			t.Errorf("bzip2 %q: status %d, want %d: %s", tt.args, status, tt.status, stderr)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"io"
+	"os"
+	"unsafe"
+
+	"github.com/pedroalbanese/bzip2/internal/fileio"
+)
+
+const (
+	directAlign = 4096    // alignment required by O_DIRECT on most devices
+	directChunk = 1 << 20 // size of each aligned transfer
+)
+
+// alignedBuffer returns a buffer of size bytes whose address is a
+// multiple of directAlign
+func alignedBuffer(size int) []byte {
+	buf := make([]byte, size+directAlign)
+	off := 0
+	if rem := int(uintptr(unsafe.Pointer(&buf[0])) & (directAlign - 1)); rem != 0 {
+		off = directAlign - rem
+	}
+	return buf[off : off+size]
+}
+
+// openInput opens a file for reading. With --direct-io it bypasses the
+// page cache where the platform and filesystem support it; reads must
+// go through the returned reader, which also applies --max-rate
+func openInput(name string) (*os.File, io.Reader, error) {
+	if !*directIO {
+		f, err := os.Open(name)
+		return f, throttleReader(f), err
+	}
+	f, direct, err := openDirect(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !direct {
+		return f, throttleReader(f), nil
+	}
+	return f, throttleReader(&directReader{f: f, buf: alignedBuffer(directChunk)}), nil
+}
+
+// createOutput creates a new file for writing with fileio.Create,
+// failing if it exists or is a symbolic link, and only open to its
+// owner until preserveMeta or fileio.DefaultMode sets its final mode.
+// Writes must go through the returned writer, which applies
+// --direct-io, --sparse and --max-rate, and the file must be closed
+// with closeOutput
+func createOutput(name string) (*os.File, io.Writer, error) {
+	if !*directIO {
+		f, err := fileio.Create(name)
+		if err == nil && *sparse && *decompress {
+			return f, throttleWriter(&sparseWriter{f: f}), nil
+		}
+		return f, throttleWriter(f), err
+	}
+	f, direct, err := openDirect(name, fileio.CreateFlags, fileio.CreateMode)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !direct {
+		return f, throttleWriter(f), nil
+	}
+	return f, throttleWriter(&directWriter{f: f, buf: alignedBuffer(directChunk)[:0]}), nil
+}
+
+// flushOutput writes out whatever a buffering writer still holds
+func flushOutput(w io.Writer) error {
+	if fw, ok := w.(interface{ Flush() error }); ok {
+		return fw.Flush()
+	}
+	return nil
+}
+
+// directReader reads a file opened with O_DIRECT in aligned chunks
+type directReader struct {
+	f    *os.File
+	buf  []byte
+	r, w int
+	err  error
+}
+
+func (d *directReader) Read(p []byte) (int, error) {
+	if d.r == d.w {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.r = 0
+		d.w, d.err = io.ReadFull(d.f, d.buf)
+		if d.err == io.ErrUnexpectedEOF {
+			d.err = io.EOF
+		}
+		if d.w == 0 {
+			return 0, d.err
+		}
+	}
+	n := copy(p, d.buf[d.r:d.w])
+	d.r += n
+	return n, nil
+}
+
+// directWriter writes to a file opened with O_DIRECT in aligned chunks.
+// The unaligned tail is written by Flush after dropping O_DIRECT
+type directWriter struct {
+	f   *os.File
+	buf []byte
+}
+
+func (d *directWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := copy(d.buf[len(d.buf):cap(d.buf)], p)
+		d.buf = d.buf[:len(d.buf)+n]
+		p = p[n:]
+		written += n
+		if len(d.buf) == cap(d.buf) {
+			if _, err := d.f.Write(d.buf); err != nil {
+				return written, err
+			}
+			d.buf = d.buf[:0]
+		}
+	}
+	return written, nil
+}
+
+// Flush writes any buffered data. No further writes may follow
+func (d *directWriter) Flush() error {
+	if len(d.buf) == 0 {
+		return nil
+	}
+	if err := clearDirect(d.f); err != nil {
+		return err
+	}
+	_, err := d.f.Write(d.buf)
+	d.buf = d.buf[:0]
+	return err
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// openDirect opens a file with O_DIRECT, falling back to buffered I/O
+// on filesystems that reject it (tmpfs on older kernels, some FUSE and
+// network filesystems). It reports whether O_DIRECT is in effect
+func openDirect(name string, flag int, perm os.FileMode) (*os.File, bool, error) {
+	f, err := os.OpenFile(name, flag|syscall.O_DIRECT, perm)
+	if err == nil {
+		return f, true, nil
+	}
+	if errors.Is(err, syscall.EINVAL) {
+		f, err = os.OpenFile(name, flag, perm)
+	}
+	return f, false, err
+}
+
+// clearDirect turns O_DIRECT off on an open file, so that a final
+// unaligned write can go through
+func clearDirect(f *os.File) error {
+	fd := f.Fd()
+	fl, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_GETFL, 0)
+	if errno != 0 {
+		return errno
+	}
+	_, _, errno = syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_SETFL, fl&^syscall.O_DIRECT)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
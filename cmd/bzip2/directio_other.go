@@ -0,0 +1,23 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package main
+
+import "os"
+
+// openDirect opens a file normally: direct I/O isn't supported
+// on this platform, so --direct-io is a no-op
+func openDirect(name string, flag int, perm os.FileMode) (*os.File, bool, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	return f, false, err
+}
+
+// clearDirect is never reached without O_DIRECT
+func clearDirect(f *os.File) error {
+	return nil
+}
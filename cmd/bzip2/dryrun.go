@@ -0,0 +1,46 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// reportDryRun prints to stdout what processFile would do with -n:
+// turn inFilePath into outFilePath, overwriting it if it exists, then
+// remove or back up the original
+func reportDryRun(inFilePath, outFilePath string, overwrite bool) {
+	var b strings.Builder
+	action := "compress"
+	if *decompress {
+		action = "decompress"
+	}
+	// Names are escaped one by one, to keep the lines apart
+	in, out := safeText(inFilePath), safeText(outFilePath)
+	src, dst := in, out
+	if inFilePath == "-" {
+		src = "standard input"
+	}
+	if *stdout {
+		dst = "standard output"
+	}
+	fmt.Fprintf(&b, "would %s %s to %s\n", action, src, dst)
+	if overwrite {
+		fmt.Fprintf(&b, "would overwrite %s\n", out)
+	}
+	if !*stdout && !*keep && inFilePath != "-" {
+		if backup == "" {
+			fmt.Fprintf(&b, "would remove %s\n", in)
+		} else if name, err := backupName(inFilePath); err == nil {
+			fmt.Fprintf(&b, "would back up %s as %s\n", in, safeText(name))
+		}
+	}
+
+	traceMu.Lock()
+	fmt.Fprint(os.Stdout, b.String())
+	traceMu.Unlock()
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"rsc.io/getopt"
+)
+
+// envVars hold default arguments, read in this order before the
+// command line, as in bzip2(1)
+var envVars = []string{"BZIP2", "BZIP"}
+
+// setFlags are the names of the flags given in the configuration
+// files, the environment or on the command line. getopt calls Set on
+// the values of flags itself, so flag.Visit never sees them
+var setFlags = make(map[string]bool)
+
+// trackedValue is the value of a flag, recording in setFlags that it
+// was set
+type trackedValue struct {
+	flag.Value
+	name string
+}
+
+func (v trackedValue) Set(s string) error {
+	setFlags[v.name] = true
+	return v.Value.Set(s)
+}
+
+// IsBoolFlag keeps bool flags taking no argument
+func (v trackedValue) IsBoolFlag() bool {
+	b, ok := v.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// trackFlags makes every flag record being set, until untrackFlags
+// gives them back their values, which the help output tells apart by
+// type
+func trackFlags() {
+	flag.VisitAll(func(f *flag.Flag) { f.Value = trackedValue{f.Value, f.Name} })
+}
+
+func untrackFlags() {
+	flag.VisitAll(func(f *flag.Flag) {
+		if v, ok := f.Value.(trackedValue); ok {
+			f.Value = v.Value
+		}
+	})
+}
+
+// parseArgs applies the configuration files (see config.go), then
+// parses the arguments in the environment and args, those on the
+// command line, so the latter take precedence, and returns the
+// operands of all of them
+func parseArgs(args []string) []string {
+	flag.Usage = usage // for getopt's errors
+	trackFlags()
+	defer untrackFlags()
+	for _, name := range configFiles() {
+		if err := loadConfig(name); err != nil {
+			fmt.Fprint(os.Stderr, safeText(fmt.Sprintf("%s: %v\n", os.Args[0], err)))
+			os.Exit(exitEnv)
+		}
+	}
+
+	var files []string
+	for _, name := range envVars {
+		args := strings.Fields(os.Getenv(name))
+		if len(args) == 0 {
+			continue
+		}
+		if err := getopt.CommandLine.Parse(args); err != nil {
+			badArgs("$" + name)
+		}
+		files = append(files, flag.Args()...)
+	}
+	if err := getopt.CommandLine.Parse(args); err != nil {
+		badArgs("the command line")
+	}
+	return append(files, flag.Args()...)
+}
+
+// badArgs exits after getopt has reported an invalid option in where.
+// Everything starting with a dash is taken as options until a bare --,
+// which is how files whose names start with one have to be given
+func badArgs(where string) {
+	fmt.Fprintf(os.Stderr, "\n%s: invalid options in %s (put -- before FILEs starting with -)\n",
+		os.Args[0], where)
+	os.Exit(exitEnv)
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Exit statuses, as documented in bzip2(1). When several files fail,
+// the most serious one is reported
+const (
+	exitOK       = 0
+	exitEnv      = 1 // environmental problems: missing files, I/O...
+	exitCorrupt  = 2 // corrupt compressed data
+	exitInternal = 3 // internal error, e.g. a panic
+)
+
+// internalError is a panic recovered while processing a file
+type internalError struct {
+	v interface{}
+}
+
+func (e internalError) Error() string { return fmt.Sprintf("internal error: %v", e.v) }
+
+// recoverInternal turns a panic into an internalError in *err; it
+// has to be deferred
+func recoverInternal(err *error) {
+	if v := recover(); v != nil {
+		*err = internalError{v}
+	}
+}
+
+// catchInternal returns the result of fn, or a panic in it as an
+// internalError, for goroutines that a deferred recoverInternal up
+// the stack doesn't cover
+func catchInternal(fn func() error) (err error) {
+	defer recoverInternal(&err)
+	return fn()
+}
+
+// statusOf returns the exit status an error calls for
+func statusOf(err error) int {
+	var corrupt interface{ IsCorrupted() bool }
+	var internal interface{ IsInternal() bool }
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &internalError{}):
+		return exitInternal
+	case errors.As(err, &internal) && internal.IsInternal():
+		return exitInternal
+	case errors.As(err, &corrupt) && corrupt.IsCorrupted():
+		return exitCorrupt
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		// A truncated compressed file
+		return exitCorrupt
+	}
+	return exitEnv
+}
+
+// worse returns the more serious of two exit statuses
+func worse(a, b int) int {
+	if b > a {
+		return b
+	}
+	return a
+}
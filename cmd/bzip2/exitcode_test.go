@@ -0,0 +1,62 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pedroalbanese/bzip2/pkg/bz"
+)
+
+func TestStatusOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{os.ErrNotExist, exitEnv},
+		{bz.ErrNotBzip2, exitCorrupt},
+		{fmt.Errorf("%w: stream 2 is truncated", bz.ErrCorrupt), exitCorrupt},
+		{fmt.Errorf("a.bz2: %w", io.ErrUnexpectedEOF), exitCorrupt},
+		{internalError{"boom"}, exitInternal},
+	}
+	for _, tt := range tests {
+		if got := statusOf(tt.err); got != tt.want {
+			t.Errorf("statusOf(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+// What the tools around the decoder find wrong is a corruption too,
+// to errors.Is as to the exit status
+func TestToolsReportCorruption(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "junk.bz2", []byte("not bzip2 data"))
+	var z bytes.Buffer
+	if _, _, err := bz.Compress(&z, bytes.NewReader(bytes.Repeat([]byte("hello\n"), 100)), nil); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "short.bz2", z.Bytes()[:z.Len()-4])
+	for _, args := range [][]string{
+		{"--list", "junk.bz2"},
+		{"--list", "short.bz2"},
+		{"inspect", "junk.bz2"},
+		{"inspect", "short.bz2"},
+		{"--recover", "junk.bz2"},
+	} {
+		if _, stderr, status := runBzip2(t, dir, nil, args...); status != exitCorrupt {
+			t.Errorf("bzip2 %v: status %d, want %d: %s", args, status, exitCorrupt, stderr)
+		}
+	}
+	if err := listFile(filepath.Join(dir, "junk.bz2")); !errors.Is(err, bz.ErrCorrupt) {
+		t.Errorf("listing junk: %v, want a match for bz.ErrCorrupt", err)
+	}
+}
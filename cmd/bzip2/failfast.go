@@ -0,0 +1,72 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// With --fail-fast the first error aborts the run: no more files are
+// started and the inputs of those in flight fail their next read, so
+// every stage of their pipelines winds down
+
+// errAborted is the error of files cut short by --fail-fast
+var errAborted = errors.New("aborted after an earlier error")
+
+var (
+	abortCh   = make(chan struct{})
+	abortOnce sync.Once
+)
+
+// abortRun aborts the run, see --fail-fast
+func abortRun() {
+	abortOnce.Do(func() { close(abortCh) })
+}
+
+// aborted reports whether the run was aborted
+func aborted() bool {
+	select {
+	case <-abortCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// guardReader makes reads from r fail once the run is aborted
+func guardReader(r io.Reader) io.Reader {
+	if !*failFast {
+		return r
+	}
+	return abortReader{r}
+}
+
+// guardReaderAt is guardReader for an io.ReaderAt
+func guardReaderAt(r io.ReaderAt) io.ReaderAt {
+	if !*failFast {
+		return r
+	}
+	return abortReaderAt{r}
+}
+
+type abortReader struct{ r io.Reader }
+
+func (a abortReader) Read(p []byte) (int, error) {
+	if aborted() {
+		return 0, errAborted
+	}
+	return a.r.Read(p)
+}
+
+type abortReaderAt struct{ r io.ReaderAt }
+
+func (a abortReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if aborted() {
+		return 0, errAborted
+	}
+	return a.r.ReadAt(p, off)
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+// readFileList reads the paths listed in the file name ("-" for
+// standard input) for --files-from, each terminated by sep: a newline,
+// or NUL with -0. Empty entries are skipped
+func readFileList(name string, sep byte) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	return splitList(r, sep)
+}
+
+// splitList returns the sep-terminated entries of r; the last one
+// may lack the terminator
+func splitList(r io.Reader, sep byte) ([]string, error) {
+	var list []string
+	br := bufio.NewReader(r)
+	for {
+		entry, err := br.ReadString(sep)
+		entry = strings.TrimSuffix(entry, string(sep))
+		if sep == '\n' {
+			entry = strings.TrimSuffix(entry, "\r")
+		}
+		if entry != "" {
+			list = append(list, entry)
+		}
+		if err == io.EOF {
+			return list, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// pattern is a glob filtering the paths visited by -r. A glob with a
+// slash is matched against the whole path relative to the directory
+// given on the command line, otherwise against the last element only;
+// a trailing slash restricts it to directories
+type pattern struct {
+	glob     string
+	anchored bool
+	dirOnly  bool
+}
+
+func newPattern(s string) (pattern, error) {
+	p := pattern{glob: s}
+	if strings.HasSuffix(p.glob, "/") {
+		p.glob = strings.TrimRight(p.glob, "/")
+		p.dirOnly = true
+	}
+	if strings.Contains(p.glob, "/") {
+		p.glob = strings.TrimPrefix(p.glob, "/")
+		p.anchored = true
+	}
+	if _, err := path.Match(p.glob, ""); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// match reports whether the path rel, relative to the walk's root,
+// matches p
+func (p pattern) match(rel string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	if !p.anchored {
+		rel = path.Base(rel)
+	}
+	ok, _ := path.Match(p.glob, rel)
+	return ok
+}
+
+// patternList is a repeatable flag collecting patterns
+type patternList []pattern
+
+func (l *patternList) String() string {
+	var globs []string
+	for _, p := range *l {
+		globs = append(globs, p.glob)
+	}
+	return strings.Join(globs, ",")
+}
+
+func (l *patternList) Set(s string) error {
+	p, err := newPattern(s)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, p)
+	return nil
+}
+
+// --include and --exclude patterns
+var includes, excludes patternList
+
+// excludeFile is the --exclude-from flag: each file given adds its
+// patterns to the excludes as the arguments are parsed
+type excludeFile struct{}
+
+func (excludeFile) String() string { return "" }
+
+func (excludeFile) Set(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	lines, err := splitList(f, '\n')
+	if err != nil {
+		return err
+	}
+	for i, line := range lines {
+		if err := addExcludeRule(line); err != nil {
+			return fmt.Errorf("%s:%d: %v", name, i+1, err)
+		}
+	}
+	return nil
+}
+
+// addExcludeRule adds a line of an --exclude-from file, written as in
+// rsync(1): "#" and ";" start comments and the pattern may be
+// prefixed by "- ". Include ("+ ") rules aren't supported; --include
+// has different semantics
+func addExcludeRule(line string) error {
+	switch {
+	case strings.HasPrefix(line, "#"), strings.HasPrefix(line, ";"):
+		return nil
+	case strings.HasPrefix(line, "+ "):
+		return fmt.Errorf("include rules are not supported: %q", line)
+	}
+	return excludes.Set(strings.TrimPrefix(line, "- "))
+}
+
+// skipPath reports whether a -r walk leaves out rel: anything matching
+// an exclude pattern, and files matching none of the include patterns
+// if there are any. Excluded directories are not descended into
+func skipPath(rel string, isDir bool) bool {
+	for _, p := range excludes {
+		if p.match(rel, isDir) {
+			return true
+		}
+	}
+	if isDir || len(includes) == 0 {
+		return false
+	}
+	for _, p := range includes {
+		if p.match(rel, isDir) {
+			return false
+		}
+	}
+	return true
+}
+
+// pathDepth returns how many levels below the walk's root the relative
+// path rel is: 0 for the root itself, 1 for its entries
+func pathDepth(rel string) int {
+	if rel == "." {
+		return 0
+	}
+	return strings.Count(filepath.ToSlash(rel), "/") + 1
+}
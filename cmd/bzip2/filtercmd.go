@@ -0,0 +1,135 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// --pre-filter CMD pipes the data through CMD, run with sh -c, on its
+// way to the compressor, and --post-filter CMD pipes the decompressed
+// data through it on its way to the output, as for encryption with
+// gpg. A filter that fails, by exiting with a status other than 0 or
+// by being killed, fails the file like an I/O error would: nothing
+// of it is kept, and bzip2 exits with status 1
+
+// filterCommand returns the command running the shell command line
+func filterCommand(line string) *exec.Cmd {
+	cmd := exec.Command("sh", "-c", line)
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// filterError names the filter that failed with err
+func filterError(which, line string, err error) error {
+	return fmt.Errorf("%s %q: %v", which, line, err)
+}
+
+// filterReader reads the output of the pre-filter
+type filterReader struct {
+	cmd  *exec.Cmd
+	r    io.ReadCloser
+	done bool // the filter was waited for
+}
+
+// startPreFilter starts the --pre-filter, if any, reading r, and
+// returns its output; closing it stops the filter
+func startPreFilter(r io.Reader) (io.ReadCloser, error) {
+	if *preFilter == "" {
+		return io.NopCloser(r), nil
+	}
+	cmd := filterCommand(*preFilter)
+	cmd.Stdin = r
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, filterError("pre-filter", *preFilter, err)
+	}
+	return &filterReader{cmd: cmd, r: out}, nil
+}
+
+func (f *filterReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF && !f.done {
+		// Its output only counts as complete if it succeeded
+		f.done = true
+		if werr := f.cmd.Wait(); werr != nil {
+			return n, filterError("pre-filter", *preFilter, werr)
+		}
+	}
+	return n, err
+}
+
+func (f *filterReader) Close() error {
+	if !f.done {
+		f.done = true
+		f.cmd.Process.Kill()
+		f.cmd.Wait()
+	}
+	return nil
+}
+
+// filterWriter feeds the post-filter, whose output goes to the
+// writer it was started with
+type filterWriter struct {
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *countWriter
+}
+
+// startPostFilter starts the --post-filter, if any, writing to w.
+// Decompressed data goes to the writer of the result, which is w itself
+// without a filter, and finish is called once it's all written
+func startPostFilter(w io.Writer) (*filterWriter, error) {
+	f := &filterWriter{out: &countWriter{w: w}}
+	if *postFilter == "" {
+		return f, nil
+	}
+	f.cmd = filterCommand(*postFilter)
+	f.cmd.Stdout = f.out
+	in, err := f.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := f.cmd.Start(); err != nil {
+		return nil, filterError("post-filter", *postFilter, err)
+	}
+	f.in = in
+	return f, nil
+}
+
+// writer returns where the decompressed data goes
+func (f *filterWriter) writer() io.Writer {
+	if f.cmd == nil {
+		return f.out.w
+	}
+	return f.in
+}
+
+// finish waits for the filter after n bytes of decompressed data
+// were written with the result err, and returns the number of bytes
+// it wrote and the error of the whole
+func (f *filterWriter) finish(n int64, err error) (int64, error) {
+	if f.cmd == nil {
+		return n, err
+	}
+	f.in.Close()
+	werr := f.cmd.Wait()
+	// A filter may stop reading, as head does, and still succeed; if it
+	// failed, that explains the broken pipe
+	if isBrokenPipe(err) || errors.Is(err, os.ErrClosed) {
+		err = nil
+	}
+	if werr != nil && err == nil {
+		err = filterError("post-filter", *postFilter, werr)
+	}
+	return f.out.n, err
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// bzip2 fix-names PATH... is the zforce of bzip2: it renames the
+// compressed files among PATHs that lack a compressed suffix to add
+// .bz2, and strips the suffix from files that carry one but aren't
+// compressed. Directories are gone through with -r, and -n only
+// shows the renames. Existing files are never replaced, and volumes
+// of --split, empty and special files are left alone. The exit
+// status is 0 on success and 1 on errors
+
+func runFixNames(args []string) int {
+	var dryRun, recurse bool
+	opts, paths, err := splitOptions(args, nil)
+	for _, o := range opts {
+		switch o {
+		case "--":
+		case "--dry-run":
+			dryRun = true
+		case "--recursive":
+			recurse = true
+		default:
+			// Bundled short options, as in -rn
+			for _, c := range o[1:] {
+				switch c {
+				case 'n':
+					dryRun = true
+				case 'r':
+					recurse = true
+				default:
+					err = fmt.Errorf("unknown option %s", o)
+				}
+			}
+		}
+	}
+	if err == nil && len(paths) == 0 {
+		err = errors.New("no PATH given")
+	}
+	if err != nil {
+		log.Printf("%s fix-names: %v", os.Args[0], err)
+		return exitEnv
+	}
+
+	status := exitOK
+	fix := func(path string, fi os.FileInfo, err error) error {
+		if err == nil && fi.IsDir() {
+			if !recurse {
+				err = errors.New("is a directory (use -r to go through it)")
+			} else {
+				return nil
+			}
+		}
+		if err == nil {
+			err = fixName(path, fi, dryRun)
+		}
+		if err != nil {
+			log.Printf("%s fix-names: %s: %v", os.Args[0], safeText(path), err)
+			status = exitEnv
+		}
+		return nil
+	}
+	for _, p := range paths {
+		fi, err := os.Lstat(p)
+		if err == nil && fi.IsDir() && recurse {
+			filepath.Walk(p, fix)
+			continue
+		}
+		fix(p, fi, err)
+	}
+	return status
+}
+
+// fixName renames path, described by fi, if its suffix doesn't match
+// its contents
+func fixName(path string, fi os.FileInfo, dryRun bool) error {
+	if !fi.Mode().IsRegular() || fi.Size() == 0 {
+		return nil
+	}
+	if _, n := volumeOf(fi.Name()); n > 0 {
+		return nil
+	}
+	isBz, err := hasStreamHeader(path)
+	if err != nil {
+		return err
+	}
+	from, to := matchSuffix(fi.Name())
+	var newPath string
+	switch {
+	case isBz && from == "":
+		newPath = path + ".bz2"
+	case !isBz && from != "":
+		newPath = strings.TrimSuffix(path, from) + to
+	default:
+		return nil
+	}
+	if _, err := os.Lstat(newPath); err == nil {
+		return fmt.Errorf("can't rename to %s: file exists", safeText(newPath))
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if dryRun {
+		fmt.Printf("would rename %s to %s\n", safeText(path), safeText(newPath))
+		return nil
+	}
+	if err := os.Rename(path, newPath); err != nil {
+		return err
+	}
+	fmt.Printf("%s renamed to %s\n", safeText(path), safeText(newPath))
+	return nil
+}
+
+// hasStreamHeader reports whether the file name starts like a bzip2
+// stream
+func hasStreamHeader(name string) (bool, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	hdr := make([]byte, 4)
+	if _, err := io.ReadFull(f, hdr); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return bytes.HasPrefix(hdr, streamMagic) && hdr[3] >= '1' && hdr[3] <= '9', nil
+}
@@ -0,0 +1,266 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// bzip2 grep runs grep on each decompressed FILE in turn, feeding it
+// the data on its standard input and naming the file with --label, so
+// matches carry the right prefix. The exit status is grep's over all
+// the files: 0 if any matched, 1 if none did, 2 on errors. With -r,
+// the directories among FILEs (the current one if there are none) are
+// searched through, -R following symbolic links; the files found are
+// searched in parallel, compressed or not, and their output is kept
+// together and in the order of the walk
+
+// grepArgOpts are the grep options whose argument may be the next word
+var grepArgOpts = map[string]bool{
+	"-e": true, "-f": true, "-A": true, "-B": true, "-C": true,
+	"-m": true, "-d": true, "-D": true,
+	"--regexp": true, "--file": true, "--after-context": true,
+	"--before-context": true, "--context": true, "--max-count": true,
+	"--label": true, "--binary-files": true, "--devices": true,
+	"--directories": true, "--group-separator": true,
+}
+
+// splitGrepArgs separates the grep options, including the pattern,
+// from the FILEs
+func splitGrepArgs(args []string) (opts, files []string, err error) {
+	opts, files, err = splitOptions(args, grepArgOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := 0; i < len(opts); i++ {
+		name, attached := optionArg(opts[i], grepArgOpts)
+		switch name {
+		case "-e", "-f", "--regexp", "--file":
+			return opts, files, nil // no pattern operand
+		}
+		if grepArgOpts[name] && !attached {
+			i++
+		}
+	}
+	if len(files) == 0 {
+		return nil, nil, errors.New("no pattern given")
+	}
+	return append(opts, files[0]), files[1:], nil
+}
+
+// dropRecursive takes -r and -R out of the grep options opts, which
+// get to search the data on their standard input, reporting which
+// were given
+func dropRecursive(opts []string) (kept []string, recursive, deref bool) {
+	for i := 0; i < len(opts); i++ {
+		a := opts[i]
+		switch {
+		case a == "--" || len(a) < 2 || a[0] != '-':
+			return append(kept, opts[i:]...), recursive, deref
+		case a == "--recursive":
+			recursive = true
+			continue
+		case a == "--dereference-recursive":
+			recursive, deref = true, true
+			continue
+		case a[1] != '-':
+			// A bundle of short options, up to one taking an argument
+			b := []byte{'-'}
+			for j := 1; j < len(a); j++ {
+				if grepArgOpts["-"+a[j:j+1]] {
+					b = append(b, a[j:]...)
+					break
+				}
+				switch a[j] {
+				case 'r':
+					recursive = true
+				case 'R':
+					recursive, deref = true, true
+				default:
+					b = append(b, a[j])
+				}
+			}
+			if len(b) == 1 {
+				continue
+			}
+			a = string(b)
+		}
+		kept = append(kept, a)
+		if name, attached := optionArg(a, grepArgOpts); grepArgOpts[name] && !attached && i+1 < len(opts) {
+			i++
+			kept = append(kept, opts[i])
+		}
+	}
+	return kept, recursive, deref
+}
+
+func runGrep(args []string) int {
+	opts, files, err := splitGrepArgs(args)
+	if err != nil {
+		log.Printf("%s grep: %v", os.Args[0], err)
+		return 2
+	}
+	opts, recursive, deref := dropRecursive(opts)
+	if recursive {
+		return grepTree(opts, files, deref)
+	}
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	if len(files) > 1 {
+		// As grep does for several files, unless -h says otherwise
+		opts = append([]string{"-H"}, opts...)
+	}
+
+	status := 1
+	for _, name := range files {
+		label := name
+		if name == "-" {
+			label = "(standard input)"
+		}
+		matched, err := grepFile(name, label, opts, streamTo, os.Stdout)
+		switch {
+		case err != nil:
+			log.Printf("%s: %v", name, err)
+			status = 2
+		case matched && status == 1:
+			status = 0
+		}
+	}
+	return status
+}
+
+// grepTree searches the files under the directories among names
+func grepTree(opts, names []string, deref bool) int {
+	if len(names) == 0 {
+		names = []string{"."}
+	}
+	status := 1
+	var files []string
+	for _, name := range names {
+		err := walkTree(name, deref, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				log.Printf("%s: %v", path, err)
+				status = 2
+			} else if fi.Mode().IsRegular() || path == "-" {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("%s: %v", name, err)
+			status = 2
+		}
+	}
+	opts = append([]string{"-H"}, opts...)
+
+	// Each file gets a grep, the output of which is printed in turn.
+	// The window bounds the files searched ahead of the one printed,
+	// whose outputs are held in memory meanwhile
+	type result struct {
+		out     bytes.Buffer
+		matched bool
+		err     error
+		done    chan struct{}
+	}
+	results := make([]*result, len(files))
+	for i := range results {
+		results[i] = &result{done: make(chan struct{})}
+	}
+	workers := availableCPUs()
+	jobs := make(chan int)
+	window := make(chan struct{}, 2*workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r := results[i]
+				r.matched, r.err = grepFile(files[i], files[i], opts, readTransparent, &r.out)
+				close(r.done)
+			}
+		}()
+	}
+	go func() {
+		for i := range files {
+			window <- struct{}{}
+			jobs <- i
+		}
+		close(jobs)
+	}()
+	for i, r := range results {
+		<-r.done
+		os.Stdout.Write(r.out.Bytes())
+		<-window
+		switch {
+		case r.err != nil:
+			log.Printf("%s: %v", files[i], r.err)
+			status = 2
+		case r.matched && status == 1:
+			status = 0
+		}
+		results[i] = nil
+	}
+	wg.Wait()
+	return status
+}
+
+// readTransparent writes the data of name to w, decompressed if it is
+// compressed
+func readTransparent(w io.Writer, name string) error {
+	if name == "-" || compressed(name) {
+		return streamTo(w, name)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// grepFile runs grep with opts on the data of name, as written by
+// feed, sending its output to stdout and reporting whether anything
+// matched
+func grepFile(name, label string, opts []string,
+	feed func(io.Writer, string) error, stdout io.Writer) (bool, error) {
+	cmd := exec.Command("grep", append([]string{"--label=" + label}, opts...)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return false, err
+	}
+	if err := cmd.Start(); err != nil {
+		return false, err
+	}
+	werr := feed(in, name)
+	in.Close()
+	err = cmd.Wait()
+
+	// grep may stop reading early, as with -l or -q
+	if werr != nil && !errors.Is(werr, os.ErrClosed) && !isBrokenPipe(werr) {
+		return false, werr
+	}
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		if exit.ExitCode() == 1 {
+			return false, nil
+		}
+		return false, errors.New("grep failed")
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitGrepArgs(t *testing.T) {
+	tests := []struct {
+		args        string
+		opts, files string
+		err         bool
+	}{
+		{"foo", "foo", "", false},
+		{"foo a b", "foo", "a b", false},
+		{"-i foo a", "-i foo", "a", false},
+		{"-A 2 foo a", "-A 2 foo", "a", false},
+		{"-e foo a b", "-e foo", "a b", false},
+		{"-ie foo a", "-ie foo", "a", false},
+		{"-efoo a", "-efoo", "a", false},
+		{"--regexp=foo a", "--regexp=foo", "a", false},
+		{"-- -foo a", "-- -foo", "a", false},
+		{"-i", "", "", true},
+		{"-A", "", "", true},
+	}
+	for _, tt := range tests {
+		opts, files, err := splitGrepArgs(words(tt.args))
+		if (err != nil) != tt.err {
+			t.Errorf("splitGrepArgs(%q): error %v", tt.args, err)
+			continue
+		}
+		if !sameWords(opts, tt.opts) || !sameWords(files, tt.files) {
+			t.Errorf("splitGrepArgs(%q) = %q, %q, want %q, %q",
+				tt.args, opts, files, words(tt.opts), words(tt.files))
+		}
+	}
+}
+
+func TestDropRecursive(t *testing.T) {
+	tests := []struct {
+		opts             string
+		kept             string
+		recursive, deref bool
+	}{
+		{"-i foo", "-i foo", false, false},
+		{"-r foo", "foo", true, false},
+		{"-R foo", "foo", true, true},
+		{"--recursive foo", "foo", true, false},
+		{"--dereference-recursive foo", "foo", true, true},
+		{"-irn foo", "-in foo", true, false},
+		{"-rA 2 foo", "-A 2 foo", true, false},
+		{"-A2r foo", "-A2r foo", false, false}, // r is the argument
+		{"-e -r", "-e -r", false, false},
+		{"-- -r", "-- -r", false, false},
+		{"foo -r", "foo -r", false, false},
+	}
+	for _, tt := range tests {
+		kept, recursive, deref := dropRecursive(words(tt.opts))
+		if !sameWords(kept, tt.kept) || recursive != tt.recursive || deref != tt.deref {
+			t.Errorf("dropRecursive(%q) = %q, %v, %v, want %q, %v, %v",
+				tt.opts, kept, recursive, deref, words(tt.kept), tt.recursive, tt.deref)
+		}
+	}
+}
+
+// grep -r searches the files under a directory, compressed or not,
+// printing in the order of the walk; -R also follows symbolic links
+func TestGrepTree(t *testing.T) {
+	if _, err := exec.LookPath("grep"); err != nil {
+		t.Skip("grep not found")
+	}
+	dir := t.TempDir()
+	d := filepath.Join(dir, "d")
+	other := filepath.Join(dir, "other")
+	for _, sub := range []string{d, other} {
+		if err := os.Mkdir(sub, 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		writeCompressed(t, d, fmt.Sprintf("f%02d.bz2", i), []byte(fmt.Sprintf("match %d\nnot this\n", i)))
+	}
+	writeFile(t, d, "plain", []byte("match plain\n"))
+	writeFile(t, other, "linked", []byte("match linked\n"))
+	if err := os.Symlink(other, filepath.Join(d, "z")); err != nil {
+		t.Fatal(err)
+	}
+
+	var want bytes.Buffer
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&want, "%s:match %d\n", filepath.Join("d", fmt.Sprintf("f%02d.bz2", i)), i)
+	}
+	fmt.Fprintf(&want, "%s:match plain\n", filepath.Join("d", "plain"))
+	out, stderr, status := runBzip2(t, dir, nil, "grep", "-r", "match", "d")
+	if status != 0 || !bytes.Equal(out, want.Bytes()) {
+		t.Errorf("bzip2 grep -r: status %d: %s\n%s\nwant:\n%s", status, stderr, out, want.Bytes())
+	}
+	fmt.Fprintf(&want, "%s:match linked\n", filepath.Join("d", "z", "linked"))
+	out, stderr, status = runBzip2(t, dir, nil, "grep", "-R", "match", "d")
+	if status != 0 || !bytes.Equal(out, want.Bytes()) {
+		t.Errorf("bzip2 grep -R: status %d: %s\n%s\nwant:\n%s", status, stderr, out, want.Bytes())
+	}
+}
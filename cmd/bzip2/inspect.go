@@ -0,0 +1,110 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/pedroalbanese/bzip2/pkg/bz"
+)
+
+// bzip2 inspect dumps the structure of each FILE as scanStreams sees
+// it, without decoding anything: the header and block size of every
+// stream, the position and stored CRC of every block, and the
+// combined CRC of the footer, checked against the block CRCs. A
+// mismatch usually means a block magic found by chance inside
+// compressed data. The exit status is 0 if every FILE looks sound, 1
+// on errors reading them and 2 if a problem was found
+
+func runInspect(args []string) int {
+	opts, files, err := splitOptions(args, nil)
+	if err == nil && len(opts) > 0 && opts[0] != "--" {
+		err = fmt.Errorf("unknown option %s", opts[0])
+	}
+	if err == nil && len(files) == 0 {
+		err = errors.New("no FILE given")
+	}
+	if err != nil {
+		log.Printf("%s inspect: %v", os.Args[0], err)
+		return exitEnv
+	}
+
+	status := exitOK
+	for i, name := range files {
+		if i > 0 {
+			fmt.Println()
+		}
+		if err := inspectFile(os.Stdout, name); err != nil {
+			log.Printf("%s: %v", name, err)
+			status = worse(status, statusOf(err))
+		}
+	}
+	return status
+}
+
+// inspectFile writes the structure of name to w
+func inspectFile(w io.Writer, name string) error {
+	if name == "-" {
+		return fmt.Errorf("can't inspect standard input")
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("is a directory")
+	}
+	streams, end, err := scanStreams(f, fi.Size())
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(w, safeText(fmt.Sprintf("%s: %d bytes, %d stream(s)\n", name, fi.Size(), len(streams))))
+	var problem error
+	for i, s := range streams {
+		fmt.Fprintf(w, "stream %d at byte %d: header BZh%c, %dk blocks\n",
+			i+1, s.start, '0'+s.level, int(s.level)*100)
+		combined := uint32(0)
+		for j, bit := range s.blocks {
+			b, err := bz.ReadBits(f, bit+48, bit+80)
+			if err != nil {
+				return err
+			}
+			crc := binary.BigEndian.Uint32(b)
+			combined = bz.CombineCRC(combined, crc)
+			fmt.Fprintf(w, "  block %d at bit %d (byte %d+%d): crc 0x%08x\n",
+				j+1, bit, bit/8, bit%8, crc)
+		}
+		if s.footer < 0 {
+			fmt.Fprintf(w, "  no footer: %d block(s), truncated\n", len(s.blocks))
+			problem = fmt.Errorf("%w: stream %d is truncated", bz.ErrCorrupt, i+1)
+			continue
+		}
+		fmt.Fprintf(w, "  footer at bit %d (byte %d+%d): combined crc 0x%08x, %d block(s)\n",
+			s.footer, s.footer/8, s.footer%8, s.crc, len(s.blocks))
+		if combined != s.crc {
+			fmt.Fprintf(w, "  combined crc of the blocks is 0x%08x, mismatch\n", combined)
+			problem = fmt.Errorf("%w: combined crc mismatch in stream %d", bz.ErrCorrupt, i+1)
+		}
+	}
+	if len(streams) == 0 {
+		return bz.ErrNotBzip2
+	}
+	if end < fi.Size() && streams[len(streams)-1].footer >= 0 {
+		fmt.Fprintf(w, "trailing garbage at byte %d: %d byte(s)\n", end, fi.Size()-end)
+		problem = fmt.Errorf("%w at byte %d", bz.ErrTrailingGarbage, end)
+	}
+	return problem
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/pedroalbanese/bzip2/internal/fileio"
+)
+
+// bzip2 join PART... -o WHOLE concatenates compressed files into one,
+// the inverse of --split: a sequence of bzip2 streams is itself a
+// valid bzip2 file, so nothing needs fixing up. Each PART is decoded
+// first, and nothing is written unless all of them are intact. WHOLE
+// is written like any output (see atomic.go), and replaced only with
+// -f; - writes to standard output. The exit status is 0 on success, 1
+// on errors and 2 if a PART is corrupt
+
+// joinArgs parses the arguments of join, where the options may come
+// after the PARTs
+func joinArgs(args []string) (parts []string, out string, force bool, err error) {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--":
+			return append(parts, args[i+1:]...), out, force, nil
+		case a == "-o" || a == "--output":
+			if i+1 == len(args) {
+				return nil, "", false, fmt.Errorf("option %s requires an argument", a)
+			}
+			i++
+			out = args[i]
+		case strings.HasPrefix(a, "--output="):
+			out = a[len("--output="):]
+		case strings.HasPrefix(a, "-o"):
+			out = a[2:]
+		case a == "-f" || a == "--force":
+			force = true
+		case len(a) > 1 && a[0] == '-':
+			return nil, "", false, fmt.Errorf("unknown option %s", a)
+		default:
+			parts = append(parts, a)
+		}
+	}
+	return parts, out, force, nil
+}
+
+func runJoin(args []string) int {
+	parts, out, force, err := joinArgs(args)
+	if err == nil && out == "" {
+		err = errors.New("no output given (-o WHOLE)")
+	}
+	if err == nil && len(parts) == 0 {
+		err = errors.New("no PART given")
+	}
+	if err != nil {
+		log.Printf("%s join: %v", os.Args[0], err)
+		return exitEnv
+	}
+	if err := joinFiles(parts, out, force); err != nil {
+		log.Printf("%s join: %v", os.Args[0], err)
+		return statusOf(err)
+	}
+	return exitOK
+}
+
+// joinFiles checks parts and writes them one after the other to out
+func joinFiles(parts []string, out string, force bool) (err error) {
+	defer recoverInternal(&err)
+
+	var mode os.FileMode
+	for i, p := range parts {
+		if p == "-" {
+			return fmt.Errorf("can't join standard input")
+		}
+		if err := checkPart(p); err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		if fi, err := os.Stat(p); err == nil && i == 0 {
+			mode = fi.Mode().Perm()
+		}
+	}
+
+	if out == "-" {
+		if !force && isTerminal(os.Stdout) {
+			return fmt.Errorf("compressed data not written to a terminal (use -f to force)")
+		}
+		for _, p := range parts {
+			if err := copyPart(os.Stdout, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if _, err := os.Lstat(out); err == nil && !force {
+		return fmt.Errorf("outFile %s exists. use -f to overwrite", out)
+	}
+	tmp := fileio.TempName(out)
+	defer func() {
+		if tmp != out { // not renamed into place
+			os.Remove(tmp)
+		}
+	}()
+	f, w, err := createOutput(tmp)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var written int64
+	for _, p := range parts {
+		cw := &countWriter{w: w}
+		err := copyPart(cw, p)
+		written += cw.n
+		if err != nil {
+			return err
+		}
+	}
+	if err := closeOutput(f, w); err != nil {
+		return err
+	}
+	if err := fileio.CheckSize(tmp, written); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, out); err != nil {
+		return err
+	}
+	tmp = out
+	return nil
+}
+
+// checkPart decodes the compressed file name to make sure it's intact
+func checkPart(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("not a regular file")
+	}
+	return testParallel(f, fi.Size(), availableCPUs())
+}
+
+// copyPart copies the file name to w as is
+func copyPart(w io.Writer, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
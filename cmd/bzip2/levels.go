@@ -0,0 +1,105 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// bzip2 compare-levels FILE compresses the start of FILE, or all of it
+// with --full, at each level from 1 to 9 and prints the size, ratio
+// and time each took, with the memory bzip2(1) documents for each
+// level, per thread: 400k + 8 x block size to compress and 100k + 4 x
+// block size to decompress. Compression runs on all the CPUs, as it
+// would by default, and the sample is read once into memory, so disk
+// speed doesn't skew the times
+
+// defaultSample is how much of FILE compare-levels compresses
+const defaultSample = 8 << 20
+
+func runCompareLevels(args []string) int {
+	sample := int64(defaultSample)
+	var files []string
+	var err error
+	for i := 0; i < len(args) && err == nil; i++ {
+		switch a := args[i]; {
+		case a == "--full":
+			sample = -1
+		case a == "--sample" && i+1 < len(args):
+			i++
+			sample, err = parseSize(args[i])
+		case strings.HasPrefix(a, "--sample="):
+			sample, err = parseSize(a[len("--sample="):])
+		case a == "--":
+			files = append(files, args[i+1:]...)
+			i = len(args)
+		case len(a) > 1 && a[0] == '-':
+			err = fmt.Errorf("unknown option %s", a)
+		default:
+			files = append(files, a)
+		}
+	}
+	if err == nil && len(files) != 1 {
+		err = errors.New("one FILE expected")
+	}
+	if err == nil && sample == 0 {
+		err = errors.New("the sample can't be empty")
+	}
+	if err != nil {
+		log.Printf("%s compare-levels: %v", os.Args[0], err)
+		return exitEnv
+	}
+	if err := compareLevels(os.Stdout, files[0], sample); err != nil {
+		log.Printf("%s: %v", files[0], err)
+		return exitEnv
+	}
+	return exitOK
+}
+
+// compareLevels writes the table for the first sample bytes of name,
+// or all of it if sample is negative, to w
+func compareLevels(w io.Writer, name string, sample int64) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var in io.Reader = f
+	if sample >= 0 {
+		in = io.LimitReader(f, sample)
+	}
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	what := "all"
+	if int64(len(data)) == sample {
+		what = "the first" // perhaps all, but we didn't look further
+	}
+	fmt.Fprint(w, safeText(fmt.Sprintf("%s: %s %d bytes, on %d CPU(s)\n", name, what, len(data), availableCPUs())))
+	fmt.Fprintf(w, "level  %12s  %6s  %9s  %8s  %10s\n", "size", "saved", "time", "compress", "decompress")
+	for level := 1; level <= 9; level++ {
+		start := time.Now()
+		_, nout, err := compressParallel(io.Discard, bytes.NewReader(data), level, availableCPUs())
+		if err != nil {
+			return err
+		}
+		took := time.Since(start)
+		saved := 0.0
+		if len(data) > 0 {
+			saved = 100 * (1 - float64(nout)/float64(len(data)))
+		}
+		fmt.Fprintf(w, "   -%d  %12d  %5.1f%%  %9s  %7dk  %9dk\n", level, nout, saved,
+			took.Round(time.Millisecond), 400+8*level*100, 100+4*level*100)
+	}
+	return nil
+}
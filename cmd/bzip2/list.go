@@ -0,0 +1,101 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/pedroalbanese/bzip2/pkg/bz"
+)
+
+// --list prints a line per FILE, like gzip -l. bzip2 records no
+// uncompressed size, so each file is decoded to count it, in parallel
+// where possible; the streams and their block sizes come from
+// scanStreams
+
+const listHeader = "  compressed  uncompressed  ratio  block  streams  name\n"
+
+// listTotals accumulates the sizes of the files listed, for the
+// totals line printed when there's more than one
+var listTotals struct {
+	sync.Mutex
+	files                    int
+	compressed, uncompressed int64
+}
+
+// listFile prints the line of the compressed file name
+func listFile(name string) (err error) {
+	defer recoverInternal(&err)
+
+	if name == "-" {
+		return fmt.Errorf("can't list standard input")
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("is a directory")
+	}
+	streams, end, err := scanStreams(guardReaderAt(f), fi.Size())
+	switch {
+	case err != nil:
+		return err
+	case len(streams) == 0:
+		return bz.ErrNotBzip2
+	case streams[len(streams)-1].footer < 0:
+		return fmt.Errorf("%w: end of stream not found, file truncated?", bz.ErrCorrupt)
+	case end < fi.Size():
+		return fmt.Errorf("%w at byte %d", bz.ErrTrailingGarbage, end)
+	}
+	cw := &countWriter{w: io.Discard}
+	if err := streamTo(cw, name); err != nil {
+		return err
+	}
+
+	block := fmt.Sprintf("%dk", int(streams[0].level)*100)
+	for _, s := range streams[1:] {
+		if s.level != streams[0].level {
+			block = "mixed"
+		}
+	}
+	fmt.Fprint(os.Stdout, safeText(listLine(fi.Size(), cw.n, block, len(streams), name)))
+
+	listTotals.Lock()
+	listTotals.files++
+	listTotals.compressed += fi.Size()
+	listTotals.uncompressed += cw.n
+	listTotals.Unlock()
+	return nil
+}
+
+// listTotal prints the totals line, if more than one file was listed
+func listTotal() {
+	if listTotals.files > 1 {
+		fmt.Fprint(os.Stdout, listLine(listTotals.compressed, listTotals.uncompressed, "", 0, "(totals)"))
+	}
+}
+
+// listLine formats a line of --list; the ratio is the space saved
+func listLine(compressed, uncompressed int64, block string, streams int, name string) string {
+	ratio := 0.0
+	if uncompressed > 0 {
+		ratio = 100 * (1 - float64(compressed)/float64(uncompressed))
+	}
+	count := ""
+	if streams > 0 {
+		count = fmt.Sprint(streams)
+	}
+	return fmt.Sprintf("%12d  %12d  %4.1f%%  %5s  %7s  %s\n",
+		compressed, uncompressed, ratio, block, count, name)
+}
@@ -0,0 +1,15 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build !windows
+// +build !windows
+
+package main
+
+// longPath returns name unchanged: only Windows limits path lengths
+// below what the filesystem allows
+func longPath(name string) string {
+	return name
+}
@@ -0,0 +1,25 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build windows
+// +build windows
+
+package main
+
+import "path/filepath"
+
+// longPath returns name in a form that works beyond MAX_PATH. The os
+// package lifts the limit by adding the \\?\ prefix, but only to
+// absolute paths, so relative ones are made absolute; UNC paths
+// (\\server\share\...) already are
+func longPath(name string) string {
+	if name == "-" || filepath.IsAbs(name) {
+		return name
+	}
+	if abs, err := filepath.Abs(name); err == nil {
+		return abs
+	}
+	return name
+}
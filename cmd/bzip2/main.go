@@ -8,6 +8,7 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -19,35 +20,117 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/dsnet/compress/bzip2"
 	"rsc.io/getopt"
+
+	"github.com/pedroalbanese/bzip2/internal/fileio"
+	"github.com/pedroalbanese/bzip2/pkg/bz"
 )
 
 // Command-line flags
 var (
 	stdout     = flag.Bool("c", false, "write on standard output, keep original files unchanged")
 	decompress = flag.Bool("d", false, "decompress; see also -c and -k")
-	force      = flag.Bool("f", false, "force overwrite of output file")
+	force      = flag.Bool("f", false, "force overwrite of output file; with -dc, copy input that isn't compressed unchanged")
 	help       = flag.Bool("h", false, "print this help message")
-	verbose    = flag.Bool("v", false, "be verbose")
+	showVer    = flag.Bool("V", false, "display software version")
+	showLic    = flag.Bool("L", false, "display software version and license")
+	quiet      = flag.Bool("q", false, "suppress noncritical error messages")
 	keep       = flag.Bool("k", false, "keep original files unchanged")
-	suffix     = flag.String("S", "bz2", "use provided suffix on compressed files")
-	cores      = flag.Int("cores", 0, "number of cores to use for parallelization")
+	cores      = flag.Int("cores", 0, "number of cores to use for parallelization (default: all available)")
 	test       = flag.Bool("t", false, "test compressed file integrity")
 	compress   = flag.Bool("z", true, "compress file(s)")
+	failFast   = flag.Bool("fail-fast", false, "stop at the first error, aborting the files in progress")
+	noXattrs   = flag.Bool("no-xattrs", false, "don't copy extended attributes to output files")
+	noContext  = flag.Bool("no-context", false, "don't copy the SELinux security context to output files")
+	prompt     = flag.Bool("interactive", false, "ask before overwriting existing files, if standard input is a terminal")
+	dryRun     = flag.Bool("n", false, "only report what would be done, without touching any file")
 	level      = flag.Int("l", 9, "compression level (1 = fastest, 9 = best)")
 	recursive  = flag.Bool("r", false, "operate recursively on directories")
+	maxDepth   = flag.Int("max-depth", -1, "with -r, go at most `N` levels into the directories given; 1 is their own entries (default: no limit)")
+	followLink = flag.Bool("follow-symlinks", false, "with -r, descend into symbolic links to directories")
+	deref      = flag.Bool("dereference", false, "process the targets of symbolic links instead of skipping them")
+	directIO   = flag.Bool("direct-io", false, "bypass the page cache for file I/O where supported")
+	sparse     = flag.Bool("sparse", false, "when decompressing, leave holes in output files where the data is all zeros")
+	saveMeta   = flag.Bool("save-meta", false, "record the original name, mode and modification time in a .meta file next to each compressed file")
+	loadMeta   = flag.Bool("restore-meta", false, "when decompressing, restore the name, mode and time recorded by --save-meta")
+	selfTest   = flag.Bool("selftest", false, "check that this build compresses, decompresses and detects corruption correctly, then exit")
+	settle     = flag.Duration("settle", 2*time.Second, "with watch, compress files once left unchanged for `DURATION`")
+	preFilter  = flag.String("pre-filter", "", "pipe the data through `command`, run with sh -c, before compressing it")
+	postFilter = flag.String("post-filter", "", "pipe the decompressed data through `command`, run with sh -c, before writing it")
+	sfx        = flag.Bool("self-extract", false, "put a shell script in front of the compressed data that recreates FILE when run, making FILE.bz2.sh")
+	tarMode    = flag.Bool("tar", false, "archive each FILE, usually a directory, into FILE.tar.bz2")
+	list       = flag.Bool("list", false, "list the sizes, ratio, block size and stream count of each FILE")
+	untar      = flag.Bool("untar", false, "extract each FILE.tar.bz2 into the current directory, or the -C one")
+	pager      = flag.Bool("pager", false, "view FILEs decompressed in $PAGER, as bzless does")
+	catMode    = flag.Bool("cat", false, "decompress FILEs to standard output, one after the other, as bzcat does")
+	recovery   = flag.Bool("recover", false, "write each block found in damaged FILEs to a file of its own, as bzip2recover does")
+	partial    = flag.Bool("recover-partial", false, "when decompressing, keep the data decoded before a corrupt block rather than nothing")
+	skipLarger = flag.Bool("skip-if-larger", false, "keep the original, and no compressed copy, of files that compression doesn't shrink")
+	cpuProfile = flag.String("cpuprofile", "", "write a CPU profile to `file`")
+	memProfile = flag.String("memprofile", "", "write a heap profile to `file` on exit")
+	traceFile  = flag.String("trace", "", "write an execution trace to `file`")
+	niceness   = flag.Int("nice", 0, "run with scheduling priority `N` (-20 to 19, higher is nicer)")
+	ioClass    = flag.String("ionice", "", "run with I/O scheduling `class` idle, best-effort[:0-7] or realtime[:0-7] (Linux)")
+	small      = flag.Bool("s", false, "use less memory (slower), mostly for embedded systems")
+	outputDir  = flag.String("C", "", "write output files under `dir`, keeping their paths relative to the FILEs given")
+	completion = flag.String("completion", "", "print the completion script for `shell` bash, zsh or fish")
+	filesFrom  = flag.String("files-from", "", "read the FILEs to process from `file` (- for standard input), one per line")
+	nullSep    = flag.Bool("0", false, "entries of --files-from are terminated by NUL instead of newline")
+	maxRate    rateValue
+	maxMemory  sizeValue
+	verbose    countValue
 
 	stdin bool // Indicates if reading from standard input
 )
 
+// aliases pairs short flags with their long counterparts
+var aliases = []string{
+	"0", "null",
+	"1", "fast",
+	"9", "best",
+	"C", "output-dir",
+	"c", "stdout",
+	"d", "decompress",
+	"f", "force",
+	"k", "keep",
+	"l", "level",
+	"n", "dry-run",
+	"q", "quiet",
+	"r", "recursive",
+	"s", "small",
+	"t", "test",
+	"v", "verbose",
+	"z", "compress",
+	"h", "help",
+	"L", "license",
+	"V", "version",
+	"M", "max-memory",
+}
+
+func init() {
+	flag.Var(&suffixes, "S", "use `suffix` on compressed files; more, given as a comma-separated list or by repeating -S, are also recognized when decompressing")
+	flag.Var(&verbose, "v", "be verbose; repeat for more detail (-vv per block, -vvv internals)")
+	flag.Var(&maxRate, "max-rate", "limit file I/O to `SIZE/s` in total, e.g. 20M/s")
+	flag.Var(&includes, "include", "with -r, only process files matching `pattern` (repeatable)")
+	flag.Var(&excludes, "exclude", "with -r, skip files and directories matching `pattern` (repeatable)")
+	flag.Var(excludeFile{}, "exclude-from", "read --exclude patterns from `file`, one per line as in rsync")
+	flag.Var(&backup, "backup", "rename originals to name~ instead of removing them; --backup=numbered makes name.~N~ backups, --backup=existing (the default) only for files that have some")
+	flag.Var(&splitSize, "split", "cut compressed output into volumes FILE.bz2.001, .002... of at most `SIZE`, e.g. 650M")
+	flag.Var(&maxMemory, "M", "limit the memory held by in-flight blocks to about `SIZE`, e.g. 256M")
+}
+
 // usage displays program usage instructions
 func usage() {
+	untrackFlags()
 	fmt.Fprintf(os.Stderr, "Usage: %s [OPTION]... [FILE]...\n", os.Args[0])
-	fmt.Fprintf(os.Stderr, "Compress or uncompress FILEs (by default, compress FILEs in-place).\n\n")
+	fmt.Fprintf(os.Stderr, "%s\n\n", progSummary)
 	getopt.PrintDefaults()
 	fmt.Fprintf(os.Stderr, "\nWith no FILE, or when FILE is -, read standard input.\n")
+	fmt.Fprintf(os.Stderr, "Short options may be bundled, as in -dkv or -c9.\n")
+	fmt.Fprintf(os.Stderr, "Use -- to end options, so that FILEs may start with -.\n")
+	subcommandUsage(os.Stderr, os.Args[0])
 }
 
 // exit shows an error message and exits the program with error code
@@ -57,60 +140,84 @@ func exit(msg string) {
 	log.Fatalf("%s: check args: %s\n\n", os.Args[0], msg)
 }
 
-// setByUser checks whether a specific flag was explicitly set by the user
-func setByUser(name string) (isSet bool) {
-	flag.Visit(func(f *flag.Flag) {
-		if f.Name == name {
-			isSet = true
-		}
-	})
-	return
+// warn prints a noncritical message, unless -q was given
+func warn(format string, a ...interface{}) {
+	if !*quiet {
+		fmt.Fprint(os.Stderr, safeText(fmt.Sprintf(format, a...)))
+	}
+}
+
+// setByUser checks whether a specific flag was explicitly set by the
+// user, under its short or long name (see setFlags in env.go)
+func setByUser(name string) bool {
+	f := getopt.CommandLine.Lookup(name)
+	return f != nil && setFlags[f.Name]
 }
 
 // processFile processes a single file (compression, decompression, or test)
-// Returns an error if any issue occurs during processing
-func processFile(inFilePath string) error {
-	// Checks for conflicting flags
-	if *stdout == true && setByUser("S") == true {
-		return fmt.Errorf("stdout set, suffix not used")
-	}
-	if *stdout == true && *force == true {
-		return fmt.Errorf("stdout set, force not used")
-	}
-	if *stdout == true && *keep == true {
-		return fmt.Errorf("stdout set, keep is redundant")
-	}
+// Returns an error if any issue occurs during processing. With -C, the
+// output file keeps its path relative to base under the output directory
+func processFile(inFilePath, base string) (err error) {
+	defer recoverInternal(&err)
+
+	// -c implies -k and makes -S irrelevant, as in bzip2; -f only
+	// matters for writing to a terminal
 
 	var outFilePath string // Output file path
+	overwrite := false     // outFilePath exists and -f was given
+	var inInfo os.FileInfo // taken before reading changes the access time
+	isLink := false        // inFilePath is a symbolic link being followed
+	var sc *sidecar        // metadata from --restore-meta
+
+	// Compressed data doesn't come from a terminal, which would also
+	// mangle it on Windows
+	if inFilePath == "-" && (*decompress || *test) && !*force && isTerminal(os.Stdin) {
+		return fmt.Errorf("compressed data not read from a terminal (use -f to force)")
+	}
+
+	// Recovery mode: salvages the blocks of a damaged file
+	if *recovery {
+		return recoverFile(inFilePath)
+	}
 
 	// Test mode: verifies compressed file integrity
 	if *test {
 		var inFile *os.File
+		var in io.Reader
 		var err error
 		if inFilePath == "-" {
 			inFile = os.Stdin
+			in = inFile
 		} else {
-			inFile, err = os.Open(inFilePath)
+			inFile, in, err = openInput(inFilePath)
 			if err != nil {
 				return err
 			}
 			defer inFile.Close()
 		}
 
-		z, err := bzip2.NewReader(inFile, nil)
-		if err != nil {
-			return fmt.Errorf("corrupted file or format error: %v", err)
-		}
-		defer z.Close()
+		// Regular files can be split into blocks and verified
+		// in parallel; pipes, direct I/O and -s are read serially
+		if fi, _ := inFile.Stat(); in == io.Reader(inFile) && *cores > 1 &&
+			!*small && inFilePath != "-" && fi != nil && fi.Mode().IsRegular() {
+			err = testParallel(guardReaderAt(inFile), fi.Size(), *cores)
+			if err != nil {
+				return fmt.Errorf("test failed: %w", err)
+			}
+		} else {
+			z, err := bz.NewReader(guardReader(in))
+			if err != nil {
+				return fmt.Errorf("corrupted file or format error: %w", err)
+			}
+			defer z.Close()
 
-		_, err = io.Copy(io.Discard, z)
-		if err != nil {
-			return fmt.Errorf("test failed: %v", err)
+			_, err = copyData(io.Discard, z)
+			if err != nil {
+				return fmt.Errorf("test failed: %w", err)
+			}
 		}
 
-		if *verbose {
-			fmt.Fprintf(os.Stderr, "%s: OK\n", inFilePath)
-		}
+		tracef(verboseFiles, "%s: OK\n", inFilePath)
 		return nil
 	}
 
@@ -119,9 +226,6 @@ func processFile(inFilePath string) error {
 		if *stdout != true {
 			return fmt.Errorf("reading from stdin, can write only to stdout")
 		}
-		if setByUser("S") == true {
-			return fmt.Errorf("reading from stdin, suffix not needed")
-		}
 		stdin = true
 	} else { // read from file
 		f, err := os.Lstat(inFilePath)
@@ -134,195 +238,435 @@ func processFile(inFilePath string) error {
 		if f.IsDir() {
 			return fmt.Errorf("%s is a directory", inFilePath)
 		}
+		// Links are only read through when writing to stdout, as in
+		// bzip2, or when asked to
+		if f.Mode()&os.ModeSymlink != 0 && !*stdout {
+			if !*deref {
+				warn("%s: skipping symbolic link (use --dereference to process its target)\n",
+					inFilePath)
+				return nil
+			}
+			isLink = true
+		}
+		if inInfo, err = os.Stat(inFilePath); err != nil {
+			return err
+		}
+		if !*stdout && !claim(inFilePath, inInfo) {
+			tracef(verboseFiles, "%s: same file as one already processed, skipped\n", inFilePath)
+			return nil
+		}
+		// Removing one name of a hard-linked file leaves the others
+		// with the uncompressed data, as gzip warns
+		if n, ok := fileLinks(inInfo); ok && n > 1 && !*stdout && !*keep && !*force {
+			s := ""
+			if n > 2 {
+				s = "s"
+			}
+			return fmt.Errorf("input file %s has %d other link%s (use -k or -f)",
+				inFilePath, n-1, s)
+		}
 
 		// Determines the output destination (file)
 		if !*stdout { // write to file
-			if *suffix == "" {
-				return fmt.Errorf("suffix can't be an empty string")
-			}
-
 			// Generates output file name
-			fext := ("." + *suffix)
+			fext := ("." + suffixes.first())
 			if *decompress {
 				outFileDir, outFileName := path.Split(inFilePath)
-				if strings.HasSuffix(outFileName, fext) {
-					if len(outFileName) > len(fext) {
-						nstr := strings.SplitN(outFileName, ".", len(outFileName))
-						estr := strings.Join(nstr[0:len(nstr)-1], ".")
-						outFilePath = (outFileDir + estr)
+				// Volumes of a --split file are decompressed together
+				if whole, n := volumeOf(outFileName); n > 1 {
+					if _, err := os.Lstat(outFileDir + volumeName(whole, 1)); err == nil {
+						tracef(verboseFiles, "%s: decompressed with the first volume, skipped\n", inFilePath)
+						return nil
+					}
+					return fmt.Errorf("first volume %s not found", outFileDir+volumeName(whole, 1))
+				} else if n == 1 {
+					outFileName = whole
+				}
+				if *loadMeta {
+					if sc, err = readSidecar(inFilePath); err != nil {
+						return err
+					}
+				}
+				if sc != nil {
+					outFilePath = outFileDir + sc.name
+				} else if from, to := matchSuffix(outFileName); from != "" {
+					if len(outFileName) > len(from) {
+						outFilePath = (outFileDir +
+							strings.TrimSuffix(outFileName, from) + to)
 					} else {
-						return fmt.Errorf("can't strip suffix .%s from file %s",
-							*suffix, inFilePath)
+						return fmt.Errorf("can't strip suffix %s from file %s",
+							from, inFilePath)
 					}
 				} else {
-					fmt.Fprintf(os.Stderr, "file %s doesn't have suffix .%s\n",
-						inFilePath, *suffix)
-					fmt.Fprintf(os.Stderr, "Can't guess original name for %s -- using %s.out\n",
+					warn("file %s doesn't have suffix .%s\n",
+						inFilePath, suffixes.first())
+					warn("Can't guess original name for %s -- using %s.out\n",
 						inFilePath, inFilePath)
 					outFilePath = (outFileDir + outFileName + ".out")
 				}
 			} else {
 				if strings.HasSuffix(inFilePath, fext) {
 					return fmt.Errorf("Input file %s already has .%s suffix.",
-						inFilePath, *suffix)
+						inFilePath, suffixes.first())
+				}
+				outFilePath = inFilePath + fext
+				if *sfx {
+					outFilePath += sfxSuffix
+				}
+			}
+
+			if *outputDir != "" {
+				outFilePath, err = relocate(outFilePath, base)
+				if err != nil {
+					return err
 				}
-				outFilePath = inFilePath + "." + *suffix
+			}
+			if splitSize > 0 && !*decompress {
+				return splitFile(inFilePath, inInfo, isLink, outFilePath)
 			}
 
 			// Checks if output file already exists
 			f, err = os.Lstat(outFilePath)
 			if err == nil && f != nil {
-				if !*force {
+				// Through a link or a case-insensitive name, it may
+				// be the input itself
+				if fileio.SameFile(inInfo, outFilePath) {
+					return fmt.Errorf("input and output %s are the same file", outFilePath)
+				}
+				if !*force && !confirmOverwrite(inFilePath, outFilePath) {
 					return fmt.Errorf("outFile %s exists. use -f to overwrite", outFilePath)
 				}
 				if f.IsDir() {
 					return fmt.Errorf("outFile %s is a directory", outFilePath)
 				}
-				err = os.Remove(outFilePath)
-				if err != nil {
-					return err
-				}
+				// Replaced by the rename of the finished output
+				overwrite = true
 			}
 		}
 	}
 
-	// Creates a pipe for communication between goroutines
-	pr, pw := io.Pipe()
+	if *stdout && inInfo != nil {
+		if o, err := os.Stdout.Stat(); err == nil && os.SameFile(inInfo, o) {
+			return fmt.Errorf("input %s is also the standard output", inFilePath)
+		}
+	}
+
+	if !*decompress && *stdout && !*force && isTerminal(os.Stdout) {
+		return fmt.Errorf("compressed data not written to a terminal (use -f to force)")
+	}
+
+	if *dryRun {
+		reportDryRun(inFilePath, outFilePath, overwrite)
+		return nil
+	}
+
+	// Writes go to a temporary file until the output is complete (see
+	// atomic.go)
+	finalPath := outFilePath
+	if !*stdout {
+		outFilePath = fileio.TempName(finalPath)
+		defer func() {
+			if outFilePath != finalPath { // not renamed into place
+				os.Remove(outFilePath)
+			}
+		}()
+	}
+
+	var written int64 // bytes written to outFilePath
+	var damaged error // corruption past which nothing was decoded
 
-	var logMu sync.Mutex
-	
 	// File decompression
 	if *decompress {
-		go func() {
-			defer pw.Close()
-			var inFile *os.File
-			var err error
-			if inFilePath == "-" {
-				inFile = os.Stdin
-			} else {
-				inFile, err = os.Open(inFilePath)
-				if err != nil {
-					pw.CloseWithError(err)
-					return
-				}
-				defer inFile.Close()
+		if written, err = decompressFile(inFilePath, outFilePath); err != nil {
+			if !errors.As(err, &partialError{}) {
+				return err
 			}
-
-			_, err = io.Copy(pw, inFile)
+			damaged = err
+			warn("%s: corrupt data, keeping the %d bytes decoded before it\n", inFilePath, written)
+		}
+		if !*stdout {
+			tracef(verboseFiles, "%s: done\n", inFilePath)
+		}
+	} else { // File compression
+		var inFile *os.File
+		var in io.Reader
+		var err error
+		if inFilePath == "-" {
+			inFile = os.Stdin
+			in = inFile
+		} else {
+			inFile, in, err = openInput(inFilePath)
 			if err != nil {
-				pw.CloseWithError(err)
-				return
+				return err
 			}
-		}()
-
-		z, err := bzip2.NewReader(pr, nil)
-		if err != nil {
-			pr.Close()
-			return err
+			defer inFile.Close()
 		}
-		defer z.Close()
 
 		var outFile *os.File
+		var out io.Writer
 		if *stdout {
 			outFile = os.Stdout
+			out = outFile
 		} else {
-			outFile, err = os.Create(outFilePath)
+			outFile, out, err = createOutput(outFilePath)
 			if err != nil {
-				pr.Close()
 				return err
 			}
 			defer outFile.Close()
 		}
 
-		_, err = io.Copy(outFile, z)
-		pr.Close()
+		var stub int64
+		if *sfx {
+			if inFilePath == "-" {
+				return fmt.Errorf("standard input has no name to extract to")
+			}
+			if stub, err = writeStub(out, filepath.Base(inFilePath), inInfo.Mode().Perm(), false); err != nil {
+				return err
+			}
+		}
+
+		src, err := startPreFilter(guardReader(in))
 		if err != nil {
 			return err
 		}
+		defer src.Close()
 
-		if *verbose && !*stdout {
-			logMu.Lock()
-			fmt.Fprintf(os.Stderr, "%s: done\n", inFilePath)
-			logMu.Unlock()
+		// Reading, compression and writing overlap: see pkg/bz/parallel.go
+		nin, nout, err := compressParallel(out, src, *level, *cores)
+		if err == nil {
+			err = closeOutput(outFile, out)
+		}
+		if err != nil {
+			return err
 		}
-	} else { // File compression
-		go func() {
-			defer pw.Close()
-			var inFile *os.File
-			var err error
-			if inFilePath == "-" {
-				inFile = os.Stdin
-			} else {
-				inFile, err = os.Open(inFilePath)
-				if err != nil {
-					pw.CloseWithError(err)
-					return
-				}
-				defer inFile.Close()
-			}
 
-			z, err := bzip2.NewWriter(pw, &bzip2.WriterConfig{Level: *level})
-			if err != nil {
-				pw.CloseWithError(err)
-				return
+		written = stub + nout
+
+		compratio := (float64(nin) / float64(nout))
+		tracef(verboseFiles, "%s: %6.3f:1, %6.3f bits/byte, %5.2f%% saved, %d in, %d out.\n",
+			inFilePath,
+			compratio,
+			((1 / compratio) * 8),
+			(100 * (1 - (1 / compratio))),
+			nin, nout)
+
+		// The temporary output is dropped on return
+		if *skipLarger && !*stdout && nout >= nin {
+			tracef(verboseFiles, "%s: not smaller once compressed, left as is\n", inFilePath)
+			return nil
+		}
+	}
+
+	// The original is only removed once its replacement is known to be
+	// complete
+	if !*stdout {
+		if err := fileio.CheckSize(outFilePath, written); err != nil {
+			return err
+		}
+	}
+
+	// Carries the original's metadata over to the new file
+	if !*stdout && inFilePath != "-" {
+		if err := preserveMeta(inFilePath, inInfo, outFilePath); err != nil {
+			return err
+		}
+		if sc != nil {
+			if err := sc.apply(outFilePath, fileio.Atime(inInfo)); err != nil {
+				return err
 			}
-			defer z.Close()
+		}
+		if *sfx && !*decompress {
+			if err := makeExecutable(outFilePath); err != nil {
+				return err
+			}
+		}
+	}
 
-			_, err = io.Copy(z, inFile)
-			if err != nil {
-				pw.CloseWithError(err)
-				return
+	// Moves the finished output into place
+	if !*stdout {
+		if err := os.Rename(outFilePath, finalPath); err != nil {
+			return err
+		}
+		outFilePath = finalPath
+		if *saveMeta && !*decompress {
+			if err := writeSidecar(outFilePath, inFilePath, inInfo); err != nil {
+				return err
 			}
+		}
+	}
+
+	// The damaged original is all there is for the rest of the data
+	if damaged != nil {
+		return damaged
+	}
 
-			if *verbose {
-				var buf strings.Builder
-				compratio := (float64(z.InputOffset) / float64(z.OutputOffset))
-				fmt.Fprintf(&buf, "%s: %6.3f:1, %6.3f bits/byte, %5.2f%% saved, %d in, %d out.\n",
-					inFilePath,
-					compratio,
-					((1 / compratio) * 8),
-					(100 * (1 - (1 / compratio))),
-					z.InputOffset, z.OutputOffset)
-
-				logMu.Lock()
-				fmt.Fprint(os.Stderr, buf.String())
-				logMu.Unlock()
+	// Removes the original file if needed, once a crash can't lose
+	// its replacement
+	if !*stdout && !*keep && inFilePath != "-" {
+		if isLink && !*force {
+			warn("%s: keeping symbolic link (use -f to remove it)\n", inFilePath)
+			return nil
+		}
+		if err := fileio.SyncDir(filepath.Dir(outFilePath)); err != nil {
+			return err
+		}
+		var rest []string
+		if _, n := volumeOf(inFilePath); n == 1 && *decompress {
+			rest = restOfVolumes(inFilePath)
+		}
+		err := removeOriginal(inFilePath)
+		if err != nil {
+			return err
+		}
+		for _, v := range rest {
+			if err := removeOriginal(v); err != nil {
+				return err
 			}
-		}()
+		}
+		if sc != nil { // the sidecar goes with its compressed file
+			if err := os.Remove(inFilePath + metaSuffix); err != nil {
+				return err
+			}
+		}
+	}
 
-		var outFile *os.File
-		var err error
-		if *stdout {
-			outFile = os.Stdout
+	return nil
+}
+
+// decompressFile decompresses inFilePath ("-" for stdin) into outFilePath,
+// or to stdout with -c. Regular files are decoded block by block in
+// parallel unless their structure doesn't allow it (see pkg/bz/blocks.go).
+// Returns the number of bytes written, and a partialError if
+// --recover-partial kept those decoded before a corrupt block
+func decompressFile(inFilePath, outFilePath string) (int64, error) {
+	_, volume := volumeOf(inFilePath)
+	if inFilePath != "-" && volume != 1 && *cores > 1 && !*small && !*directIO && ioLimit == nil {
+		f, err := os.Open(inFilePath)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			return 0, err
+		}
+		var layout *bz.Layout
+		if fi.Mode().IsRegular() {
+			layout, err = bz.ScanLayout(f, fi.Size())
 		} else {
-			outFile, err = os.Create(outFilePath)
+			err = errNotSplittable
+		}
+		if err == nil {
+			var outFile *os.File
+			var out io.Writer
+			if *stdout {
+				outFile = os.Stdout
+				out = outFile
+			} else {
+				outFile, out, err = createOutput(outFilePath)
+				if err != nil {
+					return 0, err
+				}
+				defer outFile.Close()
+			}
+			pf, err := startPostFilter(out)
 			if err != nil {
-				pr.Close()
-				return err
+				return 0, err
 			}
-			defer outFile.Close()
+			n, err := decodeBlocks(guardReaderAt(f), layout, pf.writer(), *cores)
+			n, err = pf.finish(n, err)
+			if err != nil {
+				return n, keepPartial(err, outFile, out)
+			}
+			return n, closeOutput(outFile, out)
+		}
+		if err != errNotSplittable {
+			return 0, err
 		}
+		tracef(verboseDebug, "    %s: %v, decoding serially\n", inFilePath, err)
+	}
 
-		_, err = io.Copy(outFile, pr)
-		pr.Close()
+	// Read, decode and write in stages of their own (see pipeline.go)
+	var in io.Reader
+	if inFilePath == "-" {
+		in = os.Stdin
+	} else {
+		inFile, r, err := openInput(inFilePath)
 		if err != nil {
-			return err
+			return 0, err
+		}
+		defer inFile.Close()
+		in = r
+		if volume == 1 {
+			for _, v := range restOfVolumes(inFilePath) {
+				f, r, err := openInput(v)
+				if err != nil {
+					return 0, err
+				}
+				defer f.Close()
+				in = io.MultiReader(in, r)
+			}
 		}
 	}
+	sr := newStageReader(guardReader(in))
+	defer sr.Close()
+
+	// Input passed through unchanged still goes out as the decoded
+	// data would, through any --post-filter
+	zin, plain := passThrough(sr)
+	var data io.Reader = zin
+	if plain {
+		tracef(verboseFiles, "    %s: not compressed, copied unchanged\n", inFilePath)
+	} else if *partial {
+		// The serial decoder writes a block out before it gets to
+		// its CRC; this one only once the CRC matches
+		z := bz.NewParallelReader(zin, &bz.ReaderConfig{Workers: *cores, Memory: memLimit, Trace: tracef})
+		defer z.Close()
+		data = z
+	} else {
+		z, err := bz.NewReader(zin)
+		if err != nil {
+			return 0, err
+		}
+		defer z.Close()
+		data = z
+	}
 
-	// Removes the original file if needed
-	if !*stdout && !*keep && inFilePath != "-" {
-		err := os.Remove(inFilePath)
+	var err error
+	var outFile *os.File
+	var out io.Writer
+	if *stdout {
+		outFile = os.Stdout
+		out = outFile
+	} else {
+		outFile, out, err = createOutput(outFilePath)
 		if err != nil {
-			return err
+			return 0, err
 		}
+		defer outFile.Close()
 	}
 
-	return nil
+	pf, err := startPostFilter(out)
+	if err != nil {
+		return 0, err
+	}
+	sw := newStageWriter(pf.writer())
+	n, err := copyData(sw, data)
+	if cerr := sw.Close(); err == nil {
+		err = cerr
+	}
+	n, err = pf.finish(n, err)
+	if err != nil {
+		return n, keepPartial(err, outFile, out)
+	}
+	return n, closeOutput(outFile, out)
 }
 
 // main is the program's entry point
 func main() {
+	// Messages name files, whose names may not be safe to print
+	log.SetOutput(safeWriter{os.Stderr})
+
 	// Configure flags for compression levels (1–9)
 	for i := 1; i <= 9; i++ {
 		levelValue := i
@@ -337,32 +681,23 @@ func main() {
 	}
 
 	// Alias short flags with their long counterparts.
-	getopt.Aliases(
-		"1", "fast",
-		"9", "best",
-		"c", "stdout",
-		"d", "decompress",
-		"f", "force",
-		"k", "keep",
-		"r", "recursive",
-		"t", "test",
-		"v", "verbose",
-		"z", "compress",
-		"h", "help",
-	)
-
-	// Parse command-line flags
-	getopt.Parse()
-
-	// Check if someone has used '-#' for a compression level.
-	if !setByUser("l") {
-		for i := 1; i <= 9; i++ {
-			if setByUser(strconv.Itoa(i)) {
-				*level = i
-				break
-			}
+	getopt.Aliases(aliases...)
+
+	// Parse flags from $BZIP2, $BZIP and the command line, with
+	// defaults depending on whether we run as bzip2, bunzip2 or bzcat
+	applyProgName(os.Args[0])
+	args := os.Args[1:]
+	watching := false
+	if len(args) > 0 {
+		if sc := lookupSubcommand(args[0]); sc != nil && sc.run != nil {
+			os.Exit(sc.run(args[1:]))
+		} else if sc != nil {
+			watching, args = true, args[1:]
 		}
 	}
+	files := parseArgs(args)
+	compressRequested()
+	catRequested()
 
 	// Validate compression level
 	if *level < 1 || *level > 9 {
@@ -375,102 +710,304 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Generate shell completions if requested
+	if *completion != "" {
+		if err := writeCompletion(os.Stdout, *completion); err != nil {
+			exit(err.Error())
+		}
+		os.Exit(0)
+	}
+
+	// Show version or license if requested
+	if *showLic {
+		printLicense(os.Stdout, filepath.Base(os.Args[0]))
+		os.Exit(0)
+	}
+	if *showVer {
+		printVersion(os.Stdout, filepath.Base(os.Args[0]))
+		os.Exit(0)
+	}
+	if *selfTest {
+		os.Exit(runSelfTest(os.Stdout))
+	}
+
 	// Validate number of cores
-	if setByUser("cores") && (*cores < 1 || *cores > 32) {
+	// 0 is the default, all available
+	if setByUser("cores") && *cores < 0 {
 		exit("invalid number of cores")
 	}
 
+	// Lower (or raise) priorities before any work is started, so
+	// every thread the runtime creates afterwards inherits them
+	if setByUser("nice") {
+		if *niceness < -20 || *niceness > 19 {
+			exit("invalid nice value: must be between -20 and 19")
+		}
+		if err := setNice(*niceness); err != nil {
+			warn("%s: can't set priority: %v\n", os.Args[0], err)
+		}
+	}
+	if *ioClass != "" {
+		if err := setIOClass(*ioClass); err != nil {
+			warn("%s: can't set I/O priority: %v\n", os.Args[0], err)
+		}
+	}
+
+	// Throttle file I/O if requested
+	if maxRate.sizeValue > 0 {
+		ioLimit = newLimiter(int64(maxRate.sizeValue))
+	}
+
+	// Bound the blocks held in memory if requested
+	if maxMemory > 0 {
+		setMemoryLimit(int64(maxMemory), *level)
+	}
+	if *small {
+		setSmallMode()
+	}
+
+	// Start any profiles requested for this run
+	stopProfiling, err := startProfiling()
+	if err != nil {
+		log.Fatalf("%s: %v", os.Args[0], err)
+	}
+
+	// Compress what shows up in the directories given until stopped
+	if watching {
+		status := watchDirs(files)
+		stopProfiling()
+		os.Exit(status)
+	}
+
 	// Get list of files to process
-	files := flag.Args()
+	if *filesFrom != "" {
+		sep := byte('\n')
+		if *nullSep {
+			sep = 0
+		}
+		list, err := readFileList(*filesFrom, sep)
+		if err != nil {
+			log.Fatalf("%s: %s: %v", os.Args[0], *filesFrom, err)
+		}
+		files = append(files, list...)
+		if len(files) == 0 {
+			os.Exit(exitOK) // an empty list means there's nothing to do
+		}
+	}
 	if len(files) == 0 {
 		files = []string{"-"} // default to stdin
 	}
 
+	// Standard input alone goes to standard output, like in bzip2;
+	// mixed with files it still needs an explicit -c
+	if len(files) == 1 && files[0] == "-" {
+		*stdout = true
+	}
+	if splitSize > 0 && !*decompress && !*test {
+		if *stdout || *tarMode || *sfx {
+			exit("--split writes volumes, not standard output, archives or scripts")
+		}
+		if splitSize < minSplit {
+			exit("--split SIZE must be at least 1k")
+		}
+	}
+
 	// From 'go doc runtime.GOMAXPROCS':
-	// "It defaults to the value of runtime.NumCPU.
-	// If n < 1, it does not change the current setting."
-	// In fact, if the default value of cores is zero, it
-	// will use all the cores of the machine.
+	// "It defaults to the value of runtime.NumCPU."
+	// runtime.NumCPU only knows about the affinity mask, so
+	// a container with a CPU quota would be oversubscribed;
+	// default to the cgroup-effective count instead and make
+	// the scheduler agree with it.
 	if *cores <= 0 {
-		*cores = runtime.NumCPU()
+		*cores = availableCPUs()
+	}
+	runtime.GOMAXPROCS(*cores)
+
+	// Files run *cores at a time, each with up to *cores workers;
+	// without a limit of their own they share enough blocks to keep
+	// every core busy, instead of holding cores² of them between them
+	if memLimit == nil {
+		setMemoryLimit(2*int64(*cores)*bz.BlockMemory(*level), *level)
 	}
 
-	// Process each file
-	hasErrors := false
+	// Deep trees go past MAX_PATH on Windows
+	for i := range files {
+		files[i] = longPath(files[i])
+	}
+	if *outputDir != "" {
+		*outputDir = longPath(*outputDir)
+	}
+
+	// Process each file, largest first. Output to stdout has to come
+	// in the order of the FILEs, one at a time
+	if *decompress && !*test {
+		files = dropLaterVolumes(files)
+	}
+	jobs := *cores
+	if *stdout && !*test || *list {
+		jobs = 1
+	} else {
+		files = scheduleFiles(files)
+	}
+	status := exitOK
 	var mu sync.Mutex
 	var wg sync.WaitGroup
-	sem := make(chan struct{}, *cores)
+	sem := make(chan struct{}, jobs)
+	if *pager {
+		if err := startPager(); err != nil {
+			log.Fatalf("%s: pager: %v", os.Args[0], err)
+		}
+	}
 
+	// fail reports an error on name; with --fail-fast it also
+	// aborts the run, whose remaining errors would only be noise
+	fail := func(name string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if errors.Is(err, errAborted) {
+			return
+		}
+		if pagerQuit(err) {
+			abortRun() // nobody to show the rest to
+			return
+		}
+		log.Printf("%s: %v", name, err)
+		status = worse(status, statusOf(err))
+		if *failFast {
+			abortRun()
+		}
+	}
+
+	if *list {
+		fmt.Fprint(os.Stdout, listHeader)
+	}
 	for _, file := range files {
 		file := file
-		wg.Add(1)
 
+		// Take the slot here rather than in the goroutine, so
+		// files are started in the scheduled order
+		sem <- struct{}{}
+		if aborted() {
+			break
+		}
+		wg.Add(1)
 		go func(f string) {
 			defer wg.Done()
-			sem <- struct{}{}
 			defer func() { <-sem }()
 
+			if *list {
+				if err := listFile(f); err != nil {
+					fail(f, err)
+				}
+				return
+			}
+			if *tarMode {
+				if err := tarFile(f); err != nil {
+					fail(f, err)
+				}
+				return
+			}
+			if *untar {
+				if err := untarFile(f); err != nil {
+					fail(f, err)
+				}
+				return
+			}
+
 			if file == "-" {
-				err := processFile(file)
+				err := processFile(file, "")
 				if err != nil {
-					log.Printf("%s: %v", file, err)
-					hasErrors = true
+					fail(file, err)
 				}
 				return
 			}
 
 			info, err := os.Stat(file)
 			if err != nil {
-				log.Printf("%s: %v", file, err)
-				hasErrors = true
+				fail(file, err)
 				return
 			}
 
 			if info.IsDir() {
 				if *recursive {
-					err = filepath.Walk(f, func(path string, fi os.FileInfo, err error) error {
+					err = walkTree(f, *followLink, func(path string, fi os.FileInfo, err error) error {
+						if aborted() {
+							return errAborted
+						}
+						// Later volumes go with the first, which may
+						// already have removed them
+						if _, n := volumeOf(path); n > 1 && *decompress && !*test {
+							return nil
+						}
+						// and sidecars with their compressed file
+						if base := strings.TrimSuffix(path, metaSuffix); base != path && (*decompress || *test) {
+							if from, _ := matchSuffix(base); from != "" {
+								return nil
+							}
+						}
+						// and outputs still being written, which may be
+						// gone by the time they are reached
+						if fileio.IsTempName(filepath.Base(path)) {
+							return nil
+						}
 						if err != nil {
-							mu.Lock()
-							log.Printf("%s: %v", path, err)
-							hasErrors = true
-							mu.Unlock()
+							fail(path, err)
+							return nil
+						}
+						if rel, _ := filepath.Rel(f, path); rel != "." && skipPath(rel, fi.IsDir()) {
+							if fi.IsDir() {
+								return filepath.SkipDir
+							}
+							return nil
+						} else if d := pathDepth(rel); *maxDepth >= 0 && d >= *maxDepth && fi.IsDir() {
+							return filepath.SkipDir
+						} else if *maxDepth >= 0 && d > *maxDepth {
+							return nil
+						}
+						if fi.Mode()&specialMode != 0 {
+							warn("%s: skipping special file\n", path)
 							return nil
 						}
 						if !fi.IsDir() {
-							if err := processFile(path); err != nil {
-								mu.Lock()
-								log.Printf("%s: %v", path, err)
-								hasErrors = true
-								mu.Unlock()
+							// Repeated runs leave what they compressed, and
+							// its sidecar, alone
+							if !*decompress && !*test {
+								if from, _ := matchSuffix(strings.TrimSuffix(fi.Name(), metaSuffix)); from != "" {
+									tracef(verboseFiles, "%s: already has suffix %s, skipped\n", path, from)
+									return nil
+								}
+								if _, n := volumeOf(fi.Name()); n > 0 {
+									tracef(verboseFiles, "%s: volume of a compressed file, skipped\n", path)
+									return nil
+								}
+							}
+							if err := processFile(path, filepath.Dir(f)); err != nil {
+								fail(path, err)
 							}
 						}
 						return nil
 					})
 					if err != nil {
-						mu.Lock()
-						log.Printf("%s: %v", f, err)
-						hasErrors = true
-						mu.Unlock()
+						fail(f, err)
 					}
 				} else {
-					mu.Lock()
-					log.Printf("%s is a directory (use -r to process recursively)", f)
-					hasErrors = true
-					mu.Unlock()
+					fail(f, errors.New("is a directory (use -r to process recursively)"))
 				}
 			} else {
-				if err := processFile(f); err != nil {
-					mu.Lock()
-					log.Printf("%s: %v", f, err)
-					hasErrors = true
-					mu.Unlock()
+				if err := processFile(f, filepath.Dir(f)); err != nil {
+					fail(f, err)
 				}
 			}
 		}(file)
 	}
 
 	wg.Wait()
-	if hasErrors {
-		os.Exit(1)
+	if *list {
+		listTotal()
+	}
+	stopPager()
+	stopProfiling()
+	if status != exitOK {
+		os.Exit(status)
 	}
 }
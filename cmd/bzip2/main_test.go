@@ -0,0 +1,107 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// The command is tested by running the test binary as bzip2: with
+// runAsBzip2 set, TestMain runs main instead of the tests
+const runAsBzip2 = "BZIP2_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runAsBzip2) == "1" {
+		main()
+		os.Exit(exitOK)
+	}
+	os.Exit(m.Run())
+}
+
+// runBzip2 runs bzip2 with args in dir, feeding it stdin, and returns
+// its output and exit status
+func runBzip2(t *testing.T, dir string, stdin []byte, args ...string) (stdout, stderr []byte, status int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), runAsBzip2+"=1", "BZIP2=", "BZIP=")
+	cmd.Stdin = bytes.NewReader(stdin)
+	var out, errOut bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	err := cmd.Run()
+	var exit *exec.ExitError
+	switch {
+	case errors.As(err, &exit):
+		status = exit.ExitCode()
+	case err != nil:
+		t.Fatal(err)
+	}
+	return out.Bytes(), errOut.Bytes(), status
+}
+
+// binaryData holds what a text mode translation would damage: CR, LF
+// and CRLF, NUL, the ^Z that ends text on Windows, and bytes >= 0x80
+func binaryData() []byte {
+	var b bytes.Buffer
+	for i := 0; i < 4096; i++ {
+		b.WriteString("line\r\n\n\r\x00\x1a")
+		b.WriteByte(byte(i))
+	}
+	return b.Bytes()
+}
+
+// Go hands the standard handles over untranslated on every system,
+// Windows included, so data piped through bzip2 -c must come back
+// byte for byte
+func TestStdioRoundTrip(t *testing.T) {
+	data := binaryData()
+	dir := t.TempDir()
+	compressed, stderr, status := runBzip2(t, dir, data, "-c")
+	if status != exitOK {
+		t.Fatalf("bzip2 -c: status %d: %s", status, stderr)
+	}
+	if !bytes.HasPrefix(compressed, []byte("BZh9")) {
+		t.Fatalf("bzip2 -c wrote %q..., not a bzip2 stream", compressed[:8])
+	}
+	got, stderr, status := runBzip2(t, dir, compressed, "-dc")
+	if status != exitOK {
+		t.Fatalf("bzip2 -dc: status %d: %s", status, stderr)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("round trip through stdin and stdout changed the data: %d bytes in, %d out", len(data), len(got))
+	}
+}
+
+// The last -# given wins, whether it comes from $BZIP2 or the command
+// line
+func TestLevelLastWins(t *testing.T) {
+	tests := []struct {
+		env  string
+		args []string
+		want string
+	}{
+		{"", []string{"-c", "-1", "-9"}, "BZh9"},
+		{"", []string{"-c", "-9", "-1"}, "BZh1"},
+		{"-1", []string{"-c", "-9"}, "BZh9"},
+		{"-9", []string{"-c", "-1"}, "BZh1"},
+		{"-1", []string{"-c"}, "BZh1"},
+	}
+	for _, tt := range tests {
+		cmd := exec.Command(os.Args[0], tt.args...)
+		cmd.Env = append(os.Environ(), runAsBzip2+"=1", "BZIP2="+tt.env, "BZIP=")
+		cmd.Stdin = bytes.NewReader([]byte("hello\n"))
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("BZIP2=%s bzip2 %v: %v", tt.env, tt.args, err)
+		}
+		if !bytes.HasPrefix(out, []byte(tt.want)) {
+			t.Errorf("BZIP2=%s bzip2 %v wrote %q..., want %s", tt.env, tt.args, out[:4], tt.want)
+		}
+	}
+}
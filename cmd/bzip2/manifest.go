@@ -0,0 +1,145 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+
+	"github.com/pedroalbanese/bzip2/pkg/bz"
+)
+
+// bzip2 manifest writes, for each FILE, a line of JSON listing its
+// streams and blocks as scanStreams finds them: where each block
+// starts, in bits and bytes, how long it is, the CRC of its data as
+// stored in the file, and the CRC-32 (IEEE) of the bytes it spans in
+// the file. Those byte ranges overlap by the byte two blocks share;
+// with the headers and footers of the streams they cover the whole
+// file, so a copy can be checked, or a transfer resumed, block by
+// block. The exit status is as for inspect
+
+// manifest is the JSON form of a compressed file
+type manifest struct {
+	File    string           `json:"file"`
+	Size    int64            `json:"size"`
+	Streams []manifestStream `json:"streams"`
+}
+
+type manifestStream struct {
+	Offset int64           `json:"offset"` // byte offset of the header
+	Level  int             `json:"level"`
+	CRC    uint32          `json:"crc"` // combined CRC in the footer
+	End    int64           `json:"end"` // byte offset past the footer
+	Blocks []manifestBlock `json:"blocks"`
+}
+
+type manifestBlock struct {
+	Bit     int64  `json:"bit"`      // bit offset of the block magic
+	Bits    int64  `json:"bits"`     // bits up to the next magic
+	Offset  int64  `json:"offset"`   // first byte the block touches
+	Size    int64  `json:"size"`     // bytes it touches
+	CRC     uint32 `json:"crc"`      // stored CRC of the block data
+	DataCRC uint32 `json:"data_crc"` // CRC-32 of those bytes
+}
+
+func runManifest(args []string) int {
+	opts, files, err := splitOptions(args, nil)
+	if err == nil && len(opts) > 0 && opts[0] != "--" {
+		err = fmt.Errorf("unknown option %s", opts[0])
+	}
+	if err == nil && len(files) == 0 {
+		err = errors.New("no FILE given")
+	}
+	if err != nil {
+		log.Printf("%s manifest: %v", os.Args[0], err)
+		return exitEnv
+	}
+
+	status := exitOK
+	enc := json.NewEncoder(os.Stdout)
+	for _, name := range files {
+		m, err := buildManifest(name)
+		if err == nil {
+			err = enc.Encode(m)
+		}
+		if err != nil {
+			log.Printf("%s: %v", name, err)
+			status = worse(status, statusOf(err))
+		}
+	}
+	return status
+}
+
+// buildManifest returns the manifest of the compressed file name
+func buildManifest(name string) (*manifest, error) {
+	if name == "-" {
+		return nil, fmt.Errorf("can't list the blocks of standard input")
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return nil, fmt.Errorf("is a directory")
+	}
+	streams, end, err := scanStreams(f, fi.Size())
+	switch {
+	case err != nil:
+		return nil, err
+	case len(streams) == 0:
+		return nil, bz.ErrNotBzip2
+	case streams[len(streams)-1].footer < 0:
+		return nil, fmt.Errorf("%w: end of stream not found, file truncated?", bz.ErrCorrupt)
+	case end < fi.Size():
+		return nil, fmt.Errorf("%w at byte %d", bz.ErrTrailingGarbage, end)
+	}
+
+	m := &manifest{File: name, Size: fi.Size(), Streams: []manifestStream{}}
+	for _, s := range streams {
+		ms := manifestStream{
+			Offset: s.start,
+			Level:  int(s.level),
+			CRC:    s.crc,
+			End:    (s.footer + 80 + 7) / 8,
+			Blocks: []manifestBlock{},
+		}
+		for j, bit := range s.blocks {
+			next := s.footer
+			if j+1 < len(s.blocks) {
+				next = s.blocks[j+1]
+			}
+			b, err := bz.ReadBits(f, bit+48, bit+80)
+			if err != nil {
+				return nil, err
+			}
+			mb := manifestBlock{
+				Bit:    bit,
+				Bits:   next - bit,
+				Offset: bit / 8,
+				Size:   (next+7)/8 - bit/8,
+				CRC:    binary.BigEndian.Uint32(b),
+			}
+			h := crc32.NewIEEE()
+			if _, err := io.Copy(h, io.NewSectionReader(f, mb.Offset, mb.Size)); err != nil {
+				return nil, err
+			}
+			mb.DataCRC = h.Sum32()
+			ms.Blocks = append(ms.Blocks, mb)
+		}
+		m.Streams = append(m.Streams, ms)
+	}
+	return m, nil
+}
@@ -0,0 +1,19 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import "github.com/pedroalbanese/bzip2/pkg/bz"
+
+// memLimit bounds how many blocks may be held in memory at once,
+// across all the files being processed: --max-memory sets it, and
+// otherwise it defaults to two blocks per core. A nil limit means
+// no limit
+var memLimit *bz.MemoryLimit
+
+// setMemoryLimit makes in-flight blocks at level stay within limit
+// bytes; at least one block is always allowed
+func setMemoryLimit(limit int64, level int) {
+	memLimit = bz.NewMemoryLimit(limit, level)
+}
@@ -0,0 +1,27 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"os"
+
+	"github.com/pedroalbanese/bzip2/internal/fileio"
+)
+
+// preserveMeta copies the metadata of the input inFilePath, as
+// described by fi, onto the finished outFilePath, as package fileio
+// does for package bz, leaving out what --no-xattrs and --no-context
+// ask to. Failures to copy ownership and attributes are warnings,
+// those expected of an unprivileged user only shown with -v
+func preserveMeta(inFilePath string, fi os.FileInfo, outFilePath string) error {
+	return fileio.PreserveMeta(inFilePath, fi, outFilePath, &fileio.MetaOptions{
+		NoXattrs:  *noXattrs,
+		NoContext: *noContext,
+		Warn:      warn,
+		Info: func(format string, a ...interface{}) {
+			tracef(verboseFiles, format, a...)
+		},
+	})
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// Outputs end up with the mode of their input, or that of a new file
+// under the umask when they have none, not the owner-only one they are
+// written with
+func TestOutputModes(t *testing.T) {
+	defer syscall.Umask(syscall.Umask(027))
+	dir := t.TempDir()
+	writeFile(t, dir, "f", []byte("hello\n"))
+	if err := os.Chmod(filepath.Join(dir, "f"), 0604); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "d/g", []byte("world\n"))
+
+	for _, args := range [][]string{{"-k", "f"}, {"--tar", "d"}, {"--recover", "f.bz2"}} {
+		if _, stderr, status := runBzip2(t, dir, nil, args...); status != exitOK {
+			t.Fatalf("bzip2 %v: status %d: %s", args, status, stderr)
+		}
+	}
+	for name, want := range map[string]os.FileMode{
+		"f.bz2":         0604,
+		"d.tar.bz2":     0640,
+		"rec00001f.bz2": 0640,
+	} {
+		fi, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if got := fi.Mode().Perm(); got != want {
+			t.Errorf("%s has mode %#o, want %#o", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// impliedDecompress is set when the program name implies -d
+var impliedDecompress bool
+
+// progSummary says what the program does under the name it was
+// invoked with, for the usage message
+var progSummary = "Compress or uncompress FILEs (by default, compress FILEs in-place)."
+
+// applyProgName sets the defaults implied by the name the program was
+// invoked with, so it can be installed as bunzip2 and bzcat links:
+// like bzip2, a name containing "unzip" decompresses and one
+// containing "zcat" or "z2cat" decompresses to standard output. As
+// bzless or bzmore, it runs --pager, and as bzip2recover --recover
+//
+// It must be called before parsing the arguments; -z then still
+// selects compression, see compressRequested
+func applyProgName(arg0 string) {
+	name := strings.ToLower(filepath.Base(arg0))
+	name = strings.TrimSuffix(name, ".exe")
+	switch {
+	case strings.Contains(name, "recover"):
+		*recovery = true
+		progSummary = "Write each block found in damaged FILEs to a file of its own, rec00001FILE.bz2 and so on."
+		return
+	case strings.Contains(name, "unzip"):
+		*decompress = true
+		progSummary = "Decompress FILEs in-place (with -z, compress them)."
+	case strings.Contains(name, "less"), strings.Contains(name, "more"):
+		*pager = true
+		if strings.Contains(name, "more") {
+			defaultPager = "more"
+		}
+		progSummary = "View FILEs decompressed in $PAGER, one after the other."
+	case strings.Contains(name, "zcat"):
+		*decompress = true
+		*stdout = true
+		progSummary = "Decompress FILEs to standard output, one after the other."
+	default:
+		return
+	}
+	impliedDecompress = true
+	*compress = false
+}
+
+// compressRequested undoes the decompression implied by the program
+// name if -z was given
+func compressRequested() {
+	if impliedDecompress && *compress {
+		*decompress = false
+	}
+}
+
+// catRequested applies --cat and --pager, the flag forms of the bzcat
+// and bzless names
+func catRequested() {
+	if *catMode || *pager {
+		*decompress = true
+		*stdout = true
+	}
+}
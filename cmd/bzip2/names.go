@@ -0,0 +1,87 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pedroalbanese/bzip2/pkg/bz"
+)
+
+// suffixList is the -S flag. The first suffix is given to compressed
+// files, and all of them are stripped on decompression
+type suffixList struct {
+	list []string
+	set  bool // the default was replaced
+}
+
+// suffixes holds the -S suffixes, without their leading dot
+var suffixes = suffixList{list: []string{"bz2"}}
+
+func (s *suffixList) String() string { return strings.Join(s.list, ",") }
+
+func (s *suffixList) Set(v string) error {
+	if !s.set {
+		s.list, s.set = nil, true
+	}
+	for _, part := range strings.Split(v, ",") {
+		n, err := normalizeSuffix(part)
+		if err != nil {
+			return err
+		}
+		s.list = append(s.list, n)
+	}
+	return nil
+}
+
+// first returns the suffix given to compressed files
+func (s *suffixList) first() string { return s.list[0] }
+
+// matchSuffix returns the compressed suffix name ends with and its
+// replacement, or empty strings if there's none. Suffixes given with
+// -S are the only ones recognized; the longest matching one is used
+func matchSuffix(name string) (from, to string) {
+	if setByUser("S") {
+		return bz.MatchSuffix(name, suffixes.list)
+	}
+	return bz.MatchSuffix(name, nil)
+}
+
+// normalizeSuffix validates a suffix given with -S, which may be
+// written with or without its leading dot, and returns it without
+func normalizeSuffix(s string) (string, error) {
+	s = strings.TrimPrefix(s, ".")
+	if s == "" {
+		return "", errors.New("suffix can't be an empty string")
+	}
+	if strings.ContainsRune(s, '/') || strings.ContainsRune(s, os.PathSeparator) {
+		return "", errors.New("suffix can't contain a path separator")
+	}
+	for _, c := range strings.Split(s, ".") {
+		if c == "" {
+			return "", errors.New("suffix can't have empty components")
+		}
+	}
+	return s, nil
+}
+
+// relocate moves the output file name into the -C directory, at its
+// path relative to base, and creates the directories leading to it
+func relocate(name, base string) (string, error) {
+	rel, err := filepath.Rel(base, name)
+	if err != nil {
+		return "", err
+	}
+	out := filepath.Join(*outputDir, rel)
+	if !*dryRun {
+		if err := os.MkdirAll(filepath.Dir(out), 0777); err != nil {
+			return "", err
+		}
+	}
+	return out, nil
+}
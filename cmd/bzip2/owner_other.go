@@ -0,0 +1,23 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package main
+
+import "os"
+
+// fileLinks returns the number of hard links to fi, which isn't
+// known here
+func fileLinks(fi os.FileInfo) (n uint64, ok bool) {
+	return 0, false
+}
+
+// fileID returns the device and inode identifying fi, which aren't
+// known here
+func fileID(fi os.FileInfo) (id fileKey, ok bool) {
+	return fileKey{}, false
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// --pager, or the names bzless and bzmore, decompress the FILEs into
+// $PAGER through a pipe standing in for stdout. Quitting the pager
+// before the end makes the next write fail with a broken pipe (a pipe other
+// than stdout doesn't raise SIGPIPE), which quietly ends the run
+
+// defaultPager is run when $PAGER is unset; bzmore sets it to more
+var defaultPager = "less"
+
+// pagerCmd is the running pager, if any
+var pagerCmd *exec.Cmd
+
+// startPager starts the pager and points os.Stdout at it. Like less
+// and more, it does nothing unless stdout is a terminal
+func startPager() error {
+	if !isTerminal(os.Stdout) {
+		return nil
+	}
+	args := strings.Fields(os.Getenv("PAGER"))
+	if len(args) == 0 {
+		args = []string{defaultPager}
+		if _, err := exec.LookPath(defaultPager); err != nil {
+			args = []string{"more"}
+		}
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = r
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Start()
+	r.Close()
+	if err != nil {
+		w.Close()
+		return err
+	}
+	pagerCmd, os.Stdout = cmd, w
+	return nil
+}
+
+// stopPager ends the input of the pager and waits for it to be quit
+func stopPager() {
+	if pagerCmd == nil {
+		return
+	}
+	os.Stdout.Close()
+	pagerCmd.Wait()
+}
+
+// pagerQuit reports whether err comes from the pager having been quit
+// before reading everything
+func pagerQuit(err error) bool {
+	return pagerCmd != nil && isBrokenPipe(err)
+}
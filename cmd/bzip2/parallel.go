@@ -0,0 +1,31 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"io"
+
+	"github.com/pedroalbanese/bzip2/pkg/bz"
+)
+
+// compressParallel compresses r into w at level with up to workers
+// goroutines, as a sequence of independent bzip2 streams, one per
+// block (see bz.Compress). It returns the number of bytes read and
+// written
+func compressParallel(w io.Writer, r io.Reader, level, workers int) (nin, nout int64, err error) {
+	return bz.Compress(w, r, engineOptions(level, workers))
+}
+
+// countWriter counts the bytes written through it
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
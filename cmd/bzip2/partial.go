@@ -0,0 +1,42 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// With --recover-partial, -d keeps what was decoded before the first
+// corrupt block. The serial decoder writes each block as it decodes
+// it, before getting to its CRC, so input that isn't decoded by blocks
+// goes through the parallel reader instead; like the block decoder,
+// it writes blocks in order and only once their CRCs match, so the
+// output is the intact start of the data. It is moved into place like
+// a complete one, but the original is kept and the run still exits
+// with status 2
+
+// partialError is a corruption that cut decompression short, once
+// the output decoded before it was kept
+type partialError struct {
+	err error
+}
+
+func (e partialError) Error() string { return e.err.Error() }
+
+func (e partialError) Unwrap() error { return e.err }
+
+// keepPartial returns err, the error decompression into f through w
+// stopped at. Under --recover-partial a corruption instead completes
+// the output and comes back as a partialError
+func keepPartial(err error, f *os.File, w io.Writer) error {
+	if !*partial || statusOf(err) != exitCorrupt {
+		return err
+	}
+	if cerr := closeOutput(f, w); cerr != nil {
+		return cerr
+	}
+	return partialError{err}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pedroalbanese/bzip2/pkg/bz"
+)
+
+// --recover-partial keeps the blocks before the corrupt one and none
+// of it, whichever decoder runs
+func TestRecoverPartial(t *testing.T) {
+	// Text that doesn't compress much, so that the damage is only
+	// found at the CRC, once the serial decoder has written the block
+	noise := make([]byte, 150000)
+	rand.New(rand.NewSource(1)).Read(noise)
+	data := []byte(base64.StdEncoding.EncodeToString(noise))
+	const blockSize = 50000
+	var z bytes.Buffer
+	w, err := bz.NewParallelWriter(&z, &bz.Config{Level: 1, Workers: 2, BlockSize: blockSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	it := bz.Streams(bytes.NewReader(z.Bytes()))
+	var second bz.StreamInfo
+	for i := 0; i < 2 && it.Next(); i++ {
+		second = it.Stream()
+	}
+	if second.Offset == 0 {
+		t.Fatal("compressed into one stream")
+	}
+	damaged := append([]byte(nil), z.Bytes()...)
+	damaged[second.Offset+second.Size*3/4] ^= 0x55
+	want := data[:blockSize]
+
+	tests := []struct {
+		name  string
+		stdin bool
+		args  []string
+	}{
+		{"by blocks", false, []string{"-d", "--recover-partial", "--cores", "2"}},
+		{"serially", false, []string{"-d", "--recover-partial", "--cores", "1"}},
+		{"from stdin", true, []string{"-dc", "--recover-partial"}},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		var stdin []byte
+		args := tt.args
+		if tt.stdin {
+			stdin = damaged
+		} else {
+			writeFile(t, dir, "a.bz2", damaged)
+			args = append(args, "a.bz2")
+		}
+		out, stderr, status := runBzip2(t, dir, stdin, args...)
+		if status != exitCorrupt {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, status, exitCorrupt, stderr)
+		}
+		if !tt.stdin {
+			if out, err = os.ReadFile(filepath.Join(dir, "a")); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+		}
+		if !bytes.Equal(out, want) {
+			t.Errorf("%s: kept %d bytes, want the first %d", tt.name, len(out), len(want))
+		}
+	}
+}
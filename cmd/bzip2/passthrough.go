@@ -0,0 +1,30 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// With -d -c -f, as with bzcat -f, input that doesn't start like a
+// bzip2 stream is copied to standard output unchanged, as bzip2 and
+// gzip do, so that a mix of compressed and plain files can be read
+// with one command. Only the start is looked at: garbage after valid
+// streams is still reported
+
+// passThrough reports whether r, whose start is now read through the
+// returned reader, should be copied rather than decompressed
+func passThrough(r io.Reader) (io.Reader, bool) {
+	if !*stdout || !*force || *test {
+		return r, false
+	}
+	br := bufio.NewReader(r)
+	hdr, _ := br.Peek(len(streamMagic) + 1)
+	isBz := len(hdr) == len(streamMagic)+1 && bytes.HasPrefix(hdr, streamMagic) &&
+		hdr[3] >= '1' && hdr[3] <= '9'
+	return br, !isBz
+}
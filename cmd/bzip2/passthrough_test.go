@@ -0,0 +1,26 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"os/exec"
+	"testing"
+)
+
+// Plain input copied by -dcf goes through --post-filter like decoded
+// data
+func TestPassThroughPostFilter(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run the filter")
+	}
+	dir := t.TempDir()
+	out, stderr, status := runBzip2(t, dir, []byte("plain text\n"), "-dcf", "--post-filter", "tr a-z A-Z")
+	if status != exitOK {
+		t.Fatalf("bzip2 -dcf: status %d: %s", status, stderr)
+	}
+	if string(out) != "PLAIN TEXT\n" {
+		t.Errorf("bzip2 -dcf --post-filter wrote %q, want %q", out, "PLAIN TEXT\n")
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"io"
+	"sync"
+)
+
+// A file is processed in three stages: reading, the transform
+// (compression or decompression) and writing, each in its own
+// goroutine and connected by channels of buffers, so that disk reads,
+// CPU work and disk writes overlap even when a file can't be split
+// into blocks. compressParallel and decodeBlocks fan the transform
+// out to several workers; stageReader and stageWriter provide the
+// other two stages around a single-threaded transform.
+
+const (
+	stageDepth  = 4         // buffers queued between two stages
+	stageBuffer = 256 << 10 // size of each buffer
+)
+
+// stageSize returns the queue depth and buffer size of the stages,
+// which -s shrinks
+func stageSize() (depth, size int) {
+	if *small {
+		return 1, smallCopyBuffer
+	}
+	return stageDepth, stageBuffer
+}
+
+// stageReader is the read stage: a goroutine reading r ahead of the
+// transform, which consumes it through Read
+type stageReader struct {
+	full  chan []byte // filled buffers, in order
+	empty chan []byte // buffers handed back for reuse
+	stop  chan struct{}
+	once  sync.Once
+	err   error  // read error, valid once full is closed
+	buf   []byte // buffer being consumed
+	cur   []byte // unread part of buf
+}
+
+func newStageReader(r io.Reader) *stageReader {
+	depth, size := stageSize()
+	s := &stageReader{
+		full:  make(chan []byte, depth),
+		empty: make(chan []byte, depth+1),
+		stop:  make(chan struct{}),
+	}
+	for i := 0; i < depth+1; i++ {
+		s.empty <- make([]byte, size)
+	}
+	go func() {
+		defer close(s.full)
+		for {
+			var buf []byte
+			select {
+			case buf = <-s.empty:
+			case <-s.stop:
+				return
+			}
+			n, err := io.ReadFull(r, buf[:cap(buf)])
+			if n > 0 {
+				select {
+				case s.full <- buf[:n]:
+				case <-s.stop:
+					return
+				}
+			} else {
+				s.empty <- buf
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return
+			}
+			if err != nil {
+				s.err = err
+				return
+			}
+		}
+	}()
+	return s
+}
+
+func (s *stageReader) Read(p []byte) (int, error) {
+	for len(s.cur) == 0 {
+		if s.buf != nil {
+			s.empty <- s.buf
+			s.buf = nil
+		}
+		buf, ok := <-s.full
+		if !ok {
+			if s.err != nil {
+				return 0, s.err
+			}
+			return 0, io.EOF
+		}
+		s.buf, s.cur = buf, buf
+	}
+	n := copy(p, s.cur)
+	s.cur = s.cur[n:]
+	return n, nil
+}
+
+// Close stops the read stage if the transform gave up early
+func (s *stageReader) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	return nil
+}
+
+// stageWriter is the write stage: the transform writes into buffers
+// that a goroutine writes out to w in order
+type stageWriter struct {
+	full   chan []byte
+	empty  chan []byte
+	failed chan struct{} // closed on the first write error
+	done   chan struct{}
+	err    error // write error, valid once failed is closed
+	cur    []byte
+}
+
+func newStageWriter(w io.Writer) *stageWriter {
+	depth, size := stageSize()
+	s := &stageWriter{
+		full:   make(chan []byte, depth),
+		empty:  make(chan []byte, depth+1),
+		failed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	for i := 0; i < depth; i++ {
+		s.empty <- make([]byte, 0, size)
+	}
+	s.cur = make([]byte, 0, size)
+	go func() {
+		defer close(s.done)
+		for buf := range s.full {
+			if s.err == nil {
+				if _, s.err = w.Write(buf); s.err != nil {
+					close(s.failed)
+				}
+			}
+			s.empty <- buf[:0]
+		}
+	}()
+	return s
+}
+
+func (s *stageWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		select {
+		case <-s.failed:
+			return written, s.err
+		default:
+		}
+		n := copy(s.cur[len(s.cur):cap(s.cur)], p)
+		s.cur = s.cur[:len(s.cur)+n]
+		p = p[n:]
+		written += n
+		if len(s.cur) == cap(s.cur) {
+			s.full <- s.cur
+			s.cur = <-s.empty
+		}
+	}
+	return written, nil
+}
+
+// Close hands over the last buffer, waits for the write stage to
+// finish and returns its first error
+func (s *stageWriter) Close() error {
+	if len(s.cur) > 0 {
+		s.full <- s.cur
+		s.cur = nil
+	}
+	close(s.full)
+	<-s.done
+	return s.err
+}
@@ -0,0 +1,24 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// setNice sets the scheduling priority of the process
+func setNice(n int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, 0, n)
+}
+
+// setIOClass is Linux-only
+func setIOClass(spec string) error {
+	return errors.New("I/O scheduling classes are not supported on this platform")
+}
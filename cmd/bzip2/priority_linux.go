@@ -0,0 +1,91 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// I/O scheduling classes, see ioprio_set(2)
+const (
+	ioprioClassRT   = 1
+	ioprioClassBE   = 2
+	ioprioClassIdle = 3
+
+	ioprioClassShift = 13
+	ioprioWhoProcess = 1
+)
+
+// setNice sets the scheduling priority of the process. On Linux the
+// nice value belongs to each thread, so every thread the Go runtime
+// has started so far is adjusted; threads created later inherit it
+func setNice(n int) error {
+	return forEachThread(func(tid int) error {
+		return syscall.Setpriority(syscall.PRIO_PROCESS, tid, n)
+	})
+}
+
+// setIOClass sets the I/O scheduling class of the process from a
+// spec such as "idle", "best-effort:7" or "realtime:0"
+func setIOClass(spec string) error {
+	name, level := spec, 4
+	if i := strings.IndexByte(spec, ':'); i >= 0 {
+		var err error
+		name = spec[:i]
+		level, err = strconv.Atoi(spec[i+1:])
+		if err != nil || level < 0 || level > 7 {
+			return fmt.Errorf("invalid I/O priority level %q (must be 0-7)", spec[i+1:])
+		}
+	}
+
+	var class int
+	switch name {
+	case "idle", "3":
+		class, level = ioprioClassIdle, 0
+	case "best-effort", "be", "2":
+		class = ioprioClassBE
+	case "realtime", "rt", "1":
+		class = ioprioClassRT
+	default:
+		return fmt.Errorf("unknown I/O class %q (idle, best-effort or realtime)", name)
+	}
+
+	prio := uintptr(class<<ioprioClassShift | level)
+	return forEachThread(func(tid int) error {
+		_, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET,
+			ioprioWhoProcess, uintptr(tid), prio)
+		if errno != 0 {
+			return errno
+		}
+		return nil
+	})
+}
+
+// forEachThread calls fn with the ID of every thread of the process
+func forEachThread(fn func(tid int) error) error {
+	tasks, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return fn(0) // just the calling thread
+	}
+	for _, t := range tasks {
+		tid, err := strconv.Atoi(t.Name())
+		if err != nil {
+			continue
+		}
+		// Threads may have exited since the directory was read
+		if err := fn(tid); err != nil && err != syscall.ESRCH {
+			return err
+		}
+	}
+	return nil
+}
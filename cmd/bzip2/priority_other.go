@@ -0,0 +1,21 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package main
+
+import "errors"
+
+// setNice is not supported on this platform
+func setNice(n int) error {
+	return errors.New("process priorities are not supported on this platform")
+}
+
+// setIOClass is Linux-only
+func setIOClass(spec string) error {
+	return errors.New("I/O scheduling classes are not supported on this platform")
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	promptMu    sync.Mutex // one question at a time
+	promptInput *bufio.Reader
+)
+
+// confirmOverwrite asks whether the existing outFilePath may be
+// overwritten, as gzip does. It only asks with --interactive and when
+// standard input is a terminal not used for the data itself;
+// otherwise the answer is no
+func confirmOverwrite(inFilePath, outFilePath string) bool {
+	if !*prompt || *dryRun || inFilePath == "-" || !isTerminal(os.Stdin) {
+		return false
+	}
+	promptMu.Lock()
+	defer promptMu.Unlock()
+	if promptInput == nil {
+		promptInput = bufio.NewReader(os.Stdin)
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s already exists; overwrite (y or n)? ", os.Args[0], safeText(outFilePath))
+	answer, _ := promptInput.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// limiter is a token bucket shared by every file in the run, so
+// --max-rate bounds the total bandwidth and not each file's share
+type limiter struct {
+	mu    sync.Mutex
+	rate  float64 // bytes per second
+	avail float64 // bytes that may be transferred right now
+	last  time.Time
+}
+
+// ioLimit throttles file reads and writes; nil when --max-rate is unset
+var ioLimit *limiter
+
+func newLimiter(rate int64) *limiter {
+	return &limiter{rate: float64(rate), last: time.Now()}
+}
+
+// wait blocks until n more bytes may be transferred. Large requests
+// are let through at once and paid back by sleeping, so throughput
+// converges on the rate without splitting transfers
+func (l *limiter) wait(n int) {
+	l.mu.Lock()
+	now := time.Now()
+	l.avail += now.Sub(l.last).Seconds() * l.rate
+	if l.avail > l.rate { // burst of at most one second
+		l.avail = l.rate
+	}
+	l.last = now
+	l.avail -= float64(n)
+	var d time.Duration
+	if l.avail < 0 {
+		d = time.Duration(-l.avail / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	time.Sleep(d)
+}
+
+// rateReader throttles reads through the shared limiter
+type rateReader struct {
+	r io.Reader
+	l *limiter
+}
+
+func (t *rateReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if n > 0 {
+		t.l.wait(n)
+	}
+	return n, err
+}
+
+// rateWriter throttles writes through the shared limiter
+type rateWriter struct {
+	w io.Writer
+	l *limiter
+}
+
+func (t *rateWriter) Write(p []byte) (int, error) {
+	t.l.wait(len(p))
+	return t.w.Write(p)
+}
+
+// Flush forwards to the underlying writer, see flushOutput
+func (t *rateWriter) Flush() error {
+	return flushOutput(t.w)
+}
+
+// throttleReader applies --max-rate to r, if set
+func throttleReader(r io.Reader) io.Reader {
+	if ioLimit == nil {
+		return r
+	}
+	return &rateReader{r: r, l: ioLimit}
+}
+
+// throttleWriter applies --max-rate to w, if set
+func throttleWriter(w io.Writer) io.Writer {
+	if ioLimit == nil {
+		return w
+	}
+	return &rateWriter{w: w, l: ioLimit}
+}
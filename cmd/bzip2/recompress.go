@@ -0,0 +1,131 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/pedroalbanese/bzip2/internal/fileio"
+)
+
+// bzip2 recompress -LEVEL FILE.bz2... compresses FILEs again at LEVEL
+// (9 by default), as when archives made quickly at -1 are to be made
+// smaller. The data goes straight from the decoder to the encoder, and
+// the result replaces FILE only once complete (see atomic.go), with
+// its metadata; a corrupt FILE is left as is. Files with other hard
+// links are only replaced with -f, and -v reports the sizes. The exit
+// status is 0 on success, 1 on errors and 2 if a FILE is corrupt
+
+func runRecompress(args []string) int {
+	level, force, verbose := 9, false, false
+	opts, files, err := splitOptions(args, nil)
+	for _, o := range opts {
+		switch o {
+		case "--":
+		case "--force":
+			force = true
+		case "--verbose":
+			verbose = true
+		case "--fast":
+			level = 1
+		case "--best":
+			level = 9
+		default:
+			for _, c := range o[1:] {
+				switch {
+				case c >= '1' && c <= '9':
+					level = int(c - '0')
+				case c == 'f':
+					force = true
+				case c == 'v':
+					verbose = true
+				default:
+					err = fmt.Errorf("unknown option %s", o)
+				}
+			}
+		}
+	}
+	if err == nil && len(files) == 0 {
+		err = errors.New("no FILE given")
+	}
+	if err != nil {
+		log.Printf("%s recompress: %v", os.Args[0], err)
+		return exitEnv
+	}
+
+	// Decoding is parallel as with -d
+	*cores = availableCPUs()
+	status := exitOK
+	for _, name := range files {
+		before, after, err := recompressFile(name, level, force)
+		if err != nil {
+			log.Printf("%s recompress: %s: %v", os.Args[0], safeText(name), err)
+			status = worse(status, statusOf(err))
+			continue
+		}
+		if verbose {
+			fmt.Fprint(os.Stderr, safeText(fmt.Sprintf("%s: %d -> %d bytes at -%d, %5.2f%% saved\n",
+				name, before, after, level, 100*(1-float64(after)/float64(before)))))
+		}
+	}
+	return status
+}
+
+// recompressFile replaces name with its data compressed at level,
+// returning its size before and after
+func recompressFile(name string, level int, force bool) (before, after int64, err error) {
+	defer recoverInternal(&err)
+
+	fi, err := os.Lstat(name)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !fi.Mode().IsRegular() {
+		return 0, 0, errors.New("not a regular file")
+	}
+	if n, ok := fileLinks(fi); ok && n > 1 && !force {
+		return 0, 0, errors.New("has other hard links (use -f to replace it anyway)")
+	}
+
+	tmp := fileio.TempName(name)
+	defer func() {
+		if tmp != name { // not renamed into place
+			os.Remove(tmp)
+		}
+	}()
+	f, w, err := createOutput(tmp)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(catchInternal(func() error { return streamTo(pw, name) }))
+	}()
+	_, after, err = compressParallel(w, guardReader(pr), level, *cores)
+	pr.CloseWithError(err) // stops the decoder if compression failed
+	if err == nil {
+		err = closeOutput(f, w)
+	}
+	if err == nil {
+		err = fileio.CheckSize(tmp, after)
+	}
+	if err == nil {
+		err = preserveMeta(name, fi, tmp)
+	}
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	tmp = name
+	return fi.Size(), after, nil
+}
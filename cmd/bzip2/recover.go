@@ -0,0 +1,126 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pedroalbanese/bzip2/internal/fileio"
+	"github.com/pedroalbanese/bzip2/pkg/bz"
+)
+
+// --recover does what bzip2recover does: every block found in a
+// damaged file is written out as a one-block stream of its own, named
+// rec00001file.bz2, rec00002file.bz2 and so on next to the file (or
+// under -C), so that the blocks that are intact can be decompressed
+// and the rest discarded. Blocks are found by their magic, as when
+// decoding in parallel (see pkg/bz/blocks.go), so damage anywhere
+// only costs the blocks it hits
+
+// recoveredBlock is a block candidate: the bits between a block magic
+// and the next magic
+type recoveredBlock struct {
+	start, end int64
+}
+
+// minBlockBits is the least a block candidate must span to be written
+// out: its magic and CRC and a little data
+const minBlockBits = 48 + 32 + 40
+
+// recoverFile writes out the blocks of the damaged file inFilePath
+func recoverFile(inFilePath string) error {
+	if inFilePath == "-" {
+		return fmt.Errorf("can't recover blocks from standard input")
+	}
+	f, err := os.Open(inFilePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	marks, err := bz.ScanMarks(guardReaderAt(f), fi.Size())
+	if err != nil {
+		return err
+	}
+
+	// Each block runs up to the next magic, the last one possibly to
+	// the end of a truncated file
+	var runs []recoveredBlock
+	for k, m := range marks {
+		if m.End {
+			continue
+		}
+		end := fi.Size() * 8
+		if k+1 < len(marks) {
+			end = marks[k+1].Bit
+		}
+		if end-m.Bit < minBlockBits {
+			continue
+		}
+		runs = append(runs, recoveredBlock{start: m.Bit, end: end})
+	}
+	if len(runs) == 0 {
+		return fmt.Errorf("%w: no blocks found, nothing to recover", bz.ErrCorrupt)
+	}
+
+	dir, name := filepath.Split(inFilePath)
+	if *outputDir != "" {
+		dir = *outputDir
+	}
+	if !strings.HasSuffix(name, ".bz2") {
+		name += ".bz2"
+	}
+	for i, r := range runs {
+		out := filepath.Join(dir, fmt.Sprintf("rec%05d%s", i+1, name))
+		tracef(verboseFiles, "%s: block %d runs from bit %d to %d, writing %s\n",
+			inFilePath, i+1, r.start, r.end, out)
+		if *dryRun {
+			fmt.Fprint(os.Stdout, safeText(fmt.Sprintf("would write block %d of %s to %s\n",
+				i+1, inFilePath, out)))
+			continue
+		}
+		if err := writeBlock(f, r, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBlock writes the candidate r of f to the new file name as a
+// standalone stream
+func writeBlock(f *os.File, r recoveredBlock, name string) error {
+	crc, err := bz.ReadBits(f, r.start+48, r.start+80)
+	if err != nil {
+		return err
+	}
+	stream, err := bz.BlockStream(f, r.start, r.end, binary.BigEndian.Uint32(crc))
+	if err != nil {
+		return err
+	}
+	if *force {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	out, w, err := createOutput(name)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := w.Write(stream); err != nil {
+		return err
+	}
+	if err := closeOutput(out, w); err != nil {
+		return err
+	}
+	return fileio.DefaultMode(name)
+}
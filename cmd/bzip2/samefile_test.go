@@ -0,0 +1,70 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// An output that is the input under another name is refused, even
+// with -f, rather than truncating the data being read
+func TestSameFileRefused(t *testing.T) {
+	tests := []struct {
+		name string
+		link func(oldname, newname string) error
+	}{
+		{"symbolic link", os.Symlink},
+		{"hard link", os.Link},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		writeFile(t, dir, "a", binaryData())
+		if _, stderr, status := runBzip2(t, dir, nil, "a"); status != exitOK {
+			t.Fatalf("bzip2 a: status %d: %s", status, stderr)
+		}
+		z, err := os.ReadFile(filepath.Join(dir, "a.bz2"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tt.link(filepath.Join(dir, "a.bz2"), filepath.Join(dir, "a")); err != nil {
+			t.Skipf("%s: %v", tt.name, err)
+		}
+		_, stderr, status := runBzip2(t, dir, nil, "-dfk", "a.bz2")
+		if status == exitOK || !bytes.Contains(stderr, []byte("same file")) {
+			t.Errorf("%s: bzip2 -dfk a.bz2: status %d: %s", tt.name, status, stderr)
+		}
+		if got, err := os.ReadFile(filepath.Join(dir, "a.bz2")); err != nil || !bytes.Equal(got, z) {
+			t.Errorf("%s: a.bz2 changed", tt.name)
+		}
+	}
+}
+
+// So is a standard output that is the input itself
+func TestSameFileStdout(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "a")
+	writeFile(t, dir, "a", binaryData())
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cmd := exec.Command(os.Args[0], "-cf", "a")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), runAsBzip2+"=1", "BZIP2=", "BZIP=")
+	cmd.Stdout = f
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err == nil || !bytes.Contains(stderr.Bytes(), []byte("standard output")) {
+		t.Errorf("bzip2 -cf a >>a: %v: %s", err, stderr.Bytes())
+	}
+	if got, err := os.ReadFile(name); err != nil || !bytes.Equal(got, binaryData()) {
+		t.Errorf("a changed to %d bytes, %v", len(got), err)
+	}
+}
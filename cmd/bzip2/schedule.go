@@ -0,0 +1,49 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"os"
+	"sort"
+)
+
+// scheduleFiles orders the command-line operands so the largest jobs
+// start first (longest processing time first): with the files shared
+// among *cores workers, a big file picked up last would otherwise
+// keep a single worker busy long after the others are done.
+// Directories and stdin, whose size is unknown, go first; operands
+// that can't be stat'ed go last and report their error from there
+func scheduleFiles(files []string) []string {
+	const (
+		unknown = -1 // stdin and directories
+		missing = -2 // errors are reported when processed
+	)
+	sizes := make(map[string]int64, len(files))
+	for _, f := range files {
+		if f == "-" {
+			sizes[f] = unknown
+			continue
+		}
+		fi, err := os.Stat(f)
+		switch {
+		case err != nil:
+			sizes[f] = missing
+		case fi.IsDir():
+			sizes[f] = unknown
+		default:
+			sizes[f] = fi.Size()
+		}
+	}
+
+	sorted := append([]string(nil), files...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sizes[sorted[i]], sizes[sorted[j]]
+		if a == unknown || b == unknown {
+			return a == unknown && b != unknown
+		}
+		return a > b
+	})
+	return sorted
+}
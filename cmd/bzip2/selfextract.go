@@ -0,0 +1,67 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// --self-extract puts a shell script in front of the compressed data,
+// making FILE.bz2.sh, which recreates FILE in the current directory
+// when run, or with --tar extracts the archive there. Only sh, tail
+// and a bzip2 (and tar) are needed on the other end. The script skips
+// itself by byte count, so it is padded to a fixed length
+
+// sfxSuffix is added to the names of self-extracting files
+const sfxSuffix = ".sh"
+
+// stubScript is the extraction script; its arguments are the offset
+// of the data, the quoted name of the file, the command restoring its
+// mode and where the data goes. The name appears only quoted, as one
+// with a newline would end a comment
+const stubScript = `#!/bin/sh
+# Self-extracting bzip2 file: run it with sh to extract its
+# contents into the current directory.
+skip=%010[1]d
+name=%[2]s
+if [ -e "$name" ]; then
+	echo "$0: $name exists, not overwritten" >&2
+	exit 1
+fi
+tail -c +$skip "$0" | bzip2 -dc %[4]s || { rm -rf "$name"; exit 2; }
+%[3]s
+exit 0
+`
+
+// writeStub writes the extraction script for name, a file with mode
+// perm, or an archive if tar is set, to w
+func writeStub(w io.Writer, name string, perm os.FileMode, tar bool) (int64, error) {
+	quoted := "'" + strings.Replace(name, "'", `'\''`, -1) + "'"
+	// The status of a pipeline is that of its last command, so a
+	// plain file is written by bzip2 itself
+	extract, chmod := `> "$name"`, fmt.Sprintf(`chmod %o "$name"`, perm)
+	if tar {
+		extract, chmod = "| tar -xf -", ""
+	}
+	// The offset is written with a fixed width, so it doesn't change
+	// the length it's computed from
+	stub := fmt.Sprintf(stubScript, 0, quoted, chmod, extract)
+	stub = fmt.Sprintf(stubScript, len(stub)+1, quoted, chmod, extract)
+	n, err := io.WriteString(w, stub)
+	return int64(n), err
+}
+
+// makeExecutable lets whoever can read name run it
+func makeExecutable(name string) error {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	mode := fi.Mode().Perm()
+	return os.Chmod(name, mode|mode&0444>>2)
+}
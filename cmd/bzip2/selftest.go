@@ -0,0 +1,184 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+
+	"github.com/pedroalbanese/bzip2/pkg/bz"
+)
+
+// --selftest runs a few checks of this build, meant for post-install
+// scripts: the CRC against its standard check value, the decoding of
+// a stream made by the reference bzip2, round trips through the
+// parallel compressor and both decoders at every level, multi-stream
+// input, and the detection of corrupt and truncated data. It prints a
+// line per check and exits with status 3 if any fails
+
+// refStream is "hello, world\n" compressed by bzip2 1.0.8 at -9
+var refStream = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x54, 0xa4,
+	0x97, 0x84, 0x00, 0x00, 0x02, 0xd1, 0x80, 0x00, 0x10, 0x40, 0x04, 0x06,
+	0x44, 0x90, 0x80, 0x20, 0x00, 0x31, 0x00, 0x30, 0x20, 0x68, 0x62, 0x00,
+	0x49, 0xd4, 0xb2, 0x1f, 0x3f, 0x17, 0x72, 0x45, 0x38, 0x50, 0x90, 0x54,
+	0xa4, 0x97, 0x84,
+}
+
+// selfTests are the checks, in order
+var selfTests = []struct {
+	name string
+	run  func() error
+}{
+	{"crc", testCRC},
+	{"reference stream", testReference},
+	{"round trip", testRoundTrip},
+	{"multiple streams", testMultiStream},
+	{"corruption detection", testCorruption},
+}
+
+// runSelfTest runs the checks, reporting each to w, and returns the
+// exit status
+func runSelfTest(w io.Writer) int {
+	status := exitOK
+	for _, t := range selfTests {
+		if err := t.run(); err != nil {
+			fmt.Fprintf(w, "%-22s FAILED: %v\n", t.name, err)
+			status = exitInternal
+		} else {
+			fmt.Fprintf(w, "%-22s ok\n", t.name)
+		}
+	}
+	return status
+}
+
+func testCRC() error {
+	// The check value of CRC-32/BZIP2
+	if crc := bz.UpdateCRC(0, []byte("123456789")); crc != 0xfc891918 {
+		return fmt.Errorf("crc of 123456789 is 0x%08x, want 0xfc891918", crc)
+	}
+	// The block CRC stored after the magic, one block being the stream
+	b, err := bz.ReadBits(bytes.NewReader(refStream), 4*8+48, 4*8+80)
+	if err != nil {
+		return err
+	}
+	want := uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+	if crc := bz.UpdateCRC(0, []byte("hello, world\n")); crc != want {
+		return fmt.Errorf("block crc is 0x%08x, the reference stream has 0x%08x", crc, want)
+	}
+	return nil
+}
+
+func testReference() error {
+	out, err := decodeSerial(refStream)
+	if err != nil {
+		return err
+	}
+	if string(out) != "hello, world\n" {
+		return fmt.Errorf("decoded %q", out)
+	}
+	return nil
+}
+
+// selfTestData returns n bytes of input mixing text-like runs with
+// noise, deterministically
+func selfTestData(n int) []byte {
+	r := rand.New(rand.NewSource(1))
+	data := make([]byte, 0, n)
+	words := []string{"bzip2 ", "block ", "stream ", "\n", "aaaaaaaaaaaaaaaa", "0123456789"}
+	for len(data) < n {
+		if r.Intn(8) == 0 {
+			data = append(data, byte(r.Intn(256)))
+		} else {
+			data = append(data, words[r.Intn(len(words))]...)
+		}
+	}
+	return data[:n]
+}
+
+func testRoundTrip() error {
+	for _, n := range []int{0, 1, 250000} {
+		data := selfTestData(n)
+		for level := 1; level <= 9; level++ {
+			var z bytes.Buffer
+			if _, _, err := compressParallel(&z, bytes.NewReader(data), level, 2); err != nil {
+				return fmt.Errorf("level %d, %d bytes: %v", level, n, err)
+			}
+			if err := checkDecoders(z.Bytes(), data); err != nil {
+				return fmt.Errorf("level %d, %d bytes: %v", level, n, err)
+			}
+		}
+	}
+	return nil
+}
+
+func testMultiStream() error {
+	a, b := selfTestData(150000), []byte("and a second stream\n")
+	var z bytes.Buffer
+	for _, part := range [][]byte{a, b} {
+		if _, _, err := compressParallel(&z, bytes.NewReader(part), 1, 2); err != nil {
+			return err
+		}
+	}
+	z.Write(refStream)
+	return checkDecoders(z.Bytes(), append(append(a, b...), "hello, world\n"...))
+}
+
+func testCorruption() error {
+	data := selfTestData(100000)
+	var z bytes.Buffer
+	if _, _, err := compressParallel(&z, bytes.NewReader(data), 9, 1); err != nil {
+		return err
+	}
+	damaged := append([]byte(nil), z.Bytes()...)
+	damaged[len(damaged)/2] ^= 0x10
+	for what, in := range map[string][]byte{
+		"damaged":   damaged,
+		"truncated": z.Bytes()[:z.Len()-20],
+	} {
+		if _, err := decodeSerial(in); statusOf(err) != exitCorrupt {
+			return fmt.Errorf("%s stream: got %v, want a corruption error", what, err)
+		}
+		_, err := decodeParallel(bytes.NewReader(in), int64(len(in)), io.Discard, 2)
+		if err == nil {
+			return fmt.Errorf("%s stream decoded in parallel without error", what)
+		}
+	}
+	return nil
+}
+
+// checkDecoders decodes z serially and in parallel, expecting want
+func checkDecoders(z, want []byte) error {
+	out, err := decodeSerial(z)
+	if err != nil {
+		return fmt.Errorf("serial decode: %v", err)
+	}
+	if !bytes.Equal(out, want) {
+		return fmt.Errorf("serial decode: data differs")
+	}
+	var par bytes.Buffer
+	if _, err := decodeParallel(bytes.NewReader(z), int64(len(z)), &par, 2); err != nil {
+		return fmt.Errorf("parallel decode: %v", err)
+	}
+	if !bytes.Equal(par.Bytes(), want) {
+		return fmt.Errorf("parallel decode: data differs")
+	}
+	return nil
+}
+
+// decodeSerial decompresses z with a single decoder
+func decodeSerial(z []byte) ([]byte, error) {
+	r, err := bz.NewReader(bytes.NewReader(z))
+	if err != nil {
+		return nil, err
+	}
+	out, err := io.ReadAll(r)
+	if cerr := r.Close(); err == nil {
+		err = cerr
+	}
+	return out, err
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// Every check of --selftest passes on a sound build, and is reported
+func TestSelfTest(t *testing.T) {
+	for _, st := range selfTests {
+		if err := st.run(); err != nil {
+			t.Errorf("%s: %v", st.name, err)
+		}
+	}
+	out, stderr, status := runBzip2(t, t.TempDir(), nil, "--selftest")
+	if status != exitOK {
+		t.Fatalf("bzip2 --selftest: status %d: %s%s", status, out, stderr)
+	}
+	for _, st := range selfTests {
+		if line := fmt.Sprintf("%-22s ok\n", st.name); !bytes.Contains(out, []byte(line)) {
+			t.Errorf("bzip2 --selftest doesn't report %q:\n%s", line, out)
+		}
+	}
+}
+
+// A failed check makes the status 3, and the others still run
+func TestSelfTestFailure(t *testing.T) {
+	saved := selfTests
+	defer func() { selfTests = saved }()
+	selfTests = append(selfTests[:0:0], saved[:2]...)
+	selfTests[0].run = func() error { return errors.New("broken") }
+
+	var out bytes.Buffer
+	if status := runSelfTest(&out); status != exitInternal {
+		t.Errorf("runSelfTest with a failing check: status %d, want %d", status, exitInternal)
+	}
+	want := fmt.Sprintf("%-22s FAILED: broken\n%-22s ok\n", saved[0].name, saved[1].name)
+	if out.String() != want {
+		t.Errorf("runSelfTest reported:\n%s\nwant:\n%s", out.Bytes(), want)
+	}
+}
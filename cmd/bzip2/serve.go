@@ -0,0 +1,194 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pedroalbanese/bzip2/pkg/bz"
+)
+
+// bzip2 serve [--listen ADDR] [--max-requests N] answers POST
+// /compress and POST /decompress with the request body compressed or
+// decompressed, as it streams in, so other services can hand their
+// bzip2 work over HTTP. /compress takes the level (1-9) and threads
+// query parameters, the latter capped at the CPUs available. Errors
+// found before the response starts get a status of their own; later
+// ones, such as corrupt data halfway through, cut the response short,
+// so clients never take a truncated result for a complete one.
+//
+// As a compression may take every CPU, at most N requests (2 by
+// default) are processed at once, the others waiting their turn.
+// Clients get a while to send their headers and to reuse a kept-alive
+// connection; bodies and responses have no deadline, as they stream
+
+const (
+	defaultListen      = "localhost:8080" // where serve listens without --listen
+	defaultMaxRequests = 2
+
+	serveHeaderTimeout = 10 * time.Second
+	serveIdleTimeout   = 2 * time.Minute
+)
+
+func runServe(args []string) int {
+	addr, limit := defaultListen, strconv.Itoa(defaultMaxRequests)
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == "--listen" && i+1 < len(args):
+			i++
+			addr = args[i]
+		case strings.HasPrefix(a, "--listen="):
+			addr = a[len("--listen="):]
+		case a == "--max-requests" && i+1 < len(args):
+			i++
+			limit = args[i]
+		case strings.HasPrefix(a, "--max-requests="):
+			limit = a[len("--max-requests="):]
+		default:
+			log.Printf("%s serve: unknown argument %s", os.Args[0], a)
+			return exitEnv
+		}
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 1 {
+		log.Printf("%s serve: invalid --max-requests %s", os.Args[0], limit)
+		return exitEnv
+	}
+
+	sem := make(chan struct{}, n)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/compress", limitRequests(sem, serveCompress))
+	mux.HandleFunc("/decompress", limitRequests(sem, serveDecompress))
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: serveHeaderTimeout,
+		IdleTimeout:       serveIdleTimeout,
+	}
+	l, err := net.Listen("tcp", addr)
+	if err == nil {
+		log.Printf("%s serve: listening on %s", os.Args[0], l.Addr())
+		err = srv.Serve(l)
+	}
+	log.Printf("%s serve: %v", os.Args[0], err)
+	return exitEnv
+}
+
+// limitRequests runs h once one of the slots of sem is free, or not at
+// all if the client leaves first
+func limitRequests(sem chan struct{}, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case sem <- struct{}{}:
+		case <-r.Context().Done():
+			return
+		}
+		defer func() { <-sem }()
+		h(w, r)
+	}
+}
+
+// queryInt returns the query parameter name of r, def if missing
+func queryInt(r *http.Request, name string, def, min, max int) (int, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%s must be between %d and %d", name, min, max)
+	}
+	return n, nil
+}
+
+// startStream checks that r is a POST and prepares w to stream a
+// response while the body is still being read
+func startStream(w http.ResponseWriter, r *http.Request, contentType string) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "POST the data to process", http.StatusMethodNotAllowed)
+		return false
+	}
+	// HTTP/1.x normally stops reading the body once the response
+	// starts; HTTP/2 always allows this and needs no help
+	http.NewResponseController(w).EnableFullDuplex()
+	w.Header().Set("Content-Type", contentType)
+	return true
+}
+
+// abortStream logs err and, as the status has already been sent,
+// drops the connection so the client sees the response is incomplete
+func abortStream(r *http.Request, err error) {
+	log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+	panic(http.ErrAbortHandler)
+}
+
+func serveCompress(w http.ResponseWriter, r *http.Request) {
+	cpus := availableCPUs()
+	level, err := queryInt(r, "level", 9, 1, 9)
+	threads := cpus
+	if err == nil {
+		threads, err = queryInt(r, "threads", cpus, 1, 1<<16)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if threads > cpus {
+		threads = cpus
+	}
+	if !startStream(w, r, "application/x-bzip2") {
+		return
+	}
+	if _, _, err := compressParallel(w, r.Body, level, threads); err != nil {
+		abortStream(r, err)
+	}
+}
+
+func serveDecompress(w http.ResponseWriter, r *http.Request) {
+	if !startStream(w, r, "application/octet-stream") {
+		return
+	}
+	z, err := bz.NewReader(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer z.Close()
+	// Corrupt data is usually caught in the first block, before
+	// anything was sent
+	buf := make([]byte, stageBuffer)
+	n := 0
+	for err == nil && n < len(buf) {
+		var m int
+		m, err = z.Read(buf[n:])
+		n += m
+	}
+	if n == 0 && err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, werr := w.Write(buf[:n]); werr != nil {
+		abortStream(r, werr)
+	}
+	if err == nil {
+		_, err = io.CopyBuffer(w, z, buf)
+	} else if err == io.EOF {
+		err = nil
+	}
+	if err == nil {
+		err = z.Close()
+	}
+	if err != nil {
+		abortStream(r, err)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The bzip2 format has no room for the original name and times, so
+// --save-meta records them in a sidecar file next to the compressed
+// one, which --restore-meta reads back on decompression. Sidecars
+// hold "key = value" lines, like the configuration files:
+//
+//	name = "report.txt"
+//	mtime = 2025-03-01T12:00:00Z
+//	mode = 0644
+
+// metaSuffix is appended to the compressed file's name for its sidecar
+const metaSuffix = ".meta"
+
+// sidecar is the metadata kept for a compressed file
+type sidecar struct {
+	name    string // base name of the original
+	mtime   time.Time
+	mode    os.FileMode // permission bits
+	hasMode bool        // mode was recorded
+}
+
+// writeSidecar records the metadata fi of the original inFilePath for
+// its compressed copy outFilePath
+func writeSidecar(outFilePath, inFilePath string, fi os.FileInfo) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "name = %s\n", strconv.Quote(filepath.Base(inFilePath)))
+	fmt.Fprintf(&b, "mtime = %s\n", fi.ModTime().UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "mode = %#o\n", fi.Mode().Perm())
+	return os.WriteFile(outFilePath+metaSuffix, b.Bytes(), 0666)
+}
+
+// readSidecar returns the metadata recorded for the compressed file
+// inFilePath, or nil if it has no sidecar
+func readSidecar(inFilePath string) (*sidecar, error) {
+	name := inFilePath + metaSuffix
+	data, err := os.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sc := &sidecar{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("%s:%d: missing =", name, i+1)
+		}
+		key, value := strings.TrimSpace(line[:eq]), strings.TrimSpace(line[eq+1:])
+		switch key {
+		case "name":
+			sc.name, err = strconv.Unquote(value)
+			// Only a plain name: the sidecar must not send the
+			// output elsewhere
+			if err == nil && (sc.name == "" || sc.name == "." || sc.name == ".." ||
+				strings.ContainsAny(sc.name, `/\`)) {
+				err = fmt.Errorf("not a file name")
+			}
+		case "mtime":
+			sc.mtime, err = time.Parse(time.RFC3339Nano, value)
+		case "mode":
+			var m uint64
+			m, err = strconv.ParseUint(value, 0, 32)
+			sc.mode, sc.hasMode = os.FileMode(m)&os.ModePerm, true
+		default:
+			continue // from a later version
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid %s %s: %v", name, i+1, key, value, err)
+		}
+	}
+	if sc.name == "" {
+		return nil, fmt.Errorf("%s: missing name", name)
+	}
+	return sc, nil
+}
+
+// apply sets the recorded mode and modification time on name, along
+// with the access time atime; those that weren't recorded are left
+func (sc *sidecar) apply(name string, atime time.Time) error {
+	if sc.hasMode {
+		if err := os.Chmod(name, sc.mode); err != nil {
+			return err
+		}
+	}
+	if sc.mtime.IsZero() {
+		return nil
+	}
+	return os.Chtimes(name, atime, sc.mtime)
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadSidecar(t *testing.T) {
+	mtime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		data string
+		want *sidecar // nil for an error
+	}{
+		{"name = \"report.txt\"\nmtime = 2025-03-01T12:00:00Z\nmode = 0644\n",
+			&sidecar{name: "report.txt", mtime: mtime, mode: 0644, hasMode: true}},
+		{"# comment\n\n  name = \"a b\"  \nsize = 12\n", &sidecar{name: "a b"}},
+		{"name = \"a\"\nmode = 0\n", &sidecar{name: "a", hasMode: true}},
+		{"name = \"a\"\nmode = 0104755\n", &sidecar{name: "a", mode: 0755, hasMode: true}},
+		{"mtime = 2025-03-01T12:00:00Z\n", nil},
+		{"name = \"\"\n", nil},
+		{"name = \"..\"\n", nil},
+		{"name = \"../a\"\n", nil},
+		{"name = \"a/b\"\n", nil},
+		{"name = \"a\\\\b\"\n", nil},
+		{"name = a\n", nil},
+		{"name = \"a\"\nmode = rw\n", nil},
+		{"name = \"a\"\nmtime = yesterday\n", nil},
+		{"name \"a\"\n", nil},
+	}
+	dir := t.TempDir()
+	name := filepath.Join(dir, "a.bz2")
+	for _, tt := range tests {
+		if err := os.WriteFile(name+metaSuffix, []byte(tt.data), 0666); err != nil {
+			t.Fatal(err)
+		}
+		sc, err := readSidecar(name)
+		switch {
+		case tt.want == nil && err == nil:
+			t.Errorf("readSidecar of %q = %+v, want an error", tt.data, *sc)
+		case tt.want != nil && err != nil:
+			t.Errorf("readSidecar of %q: %v", tt.data, err)
+		case tt.want != nil && (sc.name != tt.want.name || !sc.mtime.Equal(tt.want.mtime) ||
+			sc.mode != tt.want.mode || sc.hasMode != tt.want.hasMode):
+			t.Errorf("readSidecar of %q = %+v, want %+v", tt.data, *sc, *tt.want)
+		}
+	}
+
+	os.Remove(name + metaSuffix)
+	if sc, err := readSidecar(name); sc != nil || err != nil {
+		t.Errorf("readSidecar without a sidecar = %v, %v, want nil, nil", sc, err)
+	}
+}
+
+// What writeSidecar records, readSidecar reads back
+func TestSidecarRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "data.txt")
+	if err := os.WriteFile(in, nil, 0640); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2024, 2, 29, 23, 59, 59, 123456789, time.UTC)
+	if err := os.Chtimes(in, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "x.bz2")
+	if err := writeSidecar(out, in, fi); err != nil {
+		t.Fatal(err)
+	}
+	sc, err := readSidecar(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.name != "data.txt" || !sc.mtime.Equal(fi.ModTime()) || sc.mode != fi.Mode().Perm() || !sc.hasMode {
+		t.Errorf("readSidecar = %+v, want data.txt, %v, %v", *sc, fi.ModTime(), fi.Mode().Perm())
+	}
+}
+
+// A tree compressed with --save-meta gets its names, modes and times
+// back from -dr --restore-meta, which doesn't take the sidecars for
+// files to decompress
+func TestSidecarRestore(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0777); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, sub, "report.txt", []byte("report\n"))
+	name := filepath.Join(sub, "report.txt")
+	mtime := time.Date(2020, 5, 17, 8, 30, 0, 0, time.UTC)
+	if err := os.Chmod(name, 0604); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(name, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if _, stderr, status := runBzip2(t, dir, nil, "-r", "--save-meta", "sub"); status != exitOK {
+		t.Fatalf("bzip2 -r --save-meta: status %d: %s", status, stderr)
+	}
+	// The name comes from the sidecar, not from the compressed file's
+	if err := os.Rename(name+".bz2", filepath.Join(sub, "r.bz2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(name+".bz2"+metaSuffix, filepath.Join(sub, "r.bz2"+metaSuffix)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, stderr, status := runBzip2(t, dir, nil, "-dr", "--restore-meta", "sub")
+	if status != exitOK || len(stderr) > 0 {
+		t.Fatalf("bzip2 -dr --restore-meta: status %d: %s", status, stderr)
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		t.Fatalf("%v; left %q", err, dirNames(t, sub))
+	}
+	if fi.Mode().Perm() != 0604 || !fi.ModTime().Equal(mtime) {
+		t.Errorf("restored %v %v, want %v %v", fi.Mode().Perm(), fi.ModTime(), os.FileMode(0604), mtime)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// parseSize parses a byte count such as "512", "64k", "1.5M" or
+// "2GiB". Suffixes are binary multiples and case-insensitive, with
+// optional "B" or "iB"
+func parseSize(s string) (int64, error) {
+	t := strings.ToLower(strings.TrimSpace(s))
+	t = strings.TrimSuffix(t, "ib")
+	t = strings.TrimSuffix(t, "b")
+	mult := int64(1)
+	if t != "" {
+		switch t[len(t)-1] {
+		case 'k':
+			mult = 1 << 10
+		case 'm':
+			mult = 1 << 20
+		case 'g':
+			mult = 1 << 30
+		case 't':
+			mult = 1 << 40
+		}
+		if mult != 1 {
+			t = t[:len(t)-1]
+		}
+	}
+	// ParseFloat takes "inf" and "nan" too, and any exponent; the
+	// negation also rules out NaN, which compares false
+	v, err := strconv.ParseFloat(t, 64)
+	v *= float64(mult)
+	if err != nil || !(v >= 0 && v < math.MaxInt64) {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(v), nil
+}
+
+// sizeValue is a flag.Value holding a byte count parsed by parseSize
+type sizeValue int64
+
+func (v *sizeValue) String() string {
+	if *v == 0 {
+		return ""
+	}
+	return strconv.FormatInt(int64(*v), 10)
+}
+
+func (v *sizeValue) Set(s string) error {
+	n, err := parseSize(s)
+	if err != nil {
+		return err
+	}
+	*v = sizeValue(n)
+	return nil
+}
+
+// rateValue is a flag.Value holding a bandwidth such as "20M/s"
+type rateValue struct {
+	sizeValue
+}
+
+func (v *rateValue) Set(s string) error {
+	return v.sizeValue.Set(strings.TrimSuffix(s, "/s"))
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int64
+	}{
+		{"512", 512},
+		{"64k", 64 << 10},
+		{"1.5M", 3 << 19},
+		{"2GiB", 2 << 30},
+		{"1tb", 1 << 40},
+		{"0", 0},
+	}
+	for _, tt := range tests {
+		if got, err := parseSize(tt.s); err != nil || got != tt.want {
+			t.Errorf("parseSize(%q) = %d, %v, want %d", tt.s, got, err, tt.want)
+		}
+	}
+	for _, s := range []string{"", "k", "x", "-1", "-inf", "inf", "nan", "1e30", "1e19", "8388608T"} {
+		if got, err := parseSize(s); err == nil {
+			t.Errorf("parseSize(%q) = %d, want an error", s, got)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"io"
+	"runtime/debug"
+)
+
+const (
+	smallCopyBuffer = 4 << 10 // io.Copy uses 32k
+	smallGCPercent  = 25      // collect well before the heap doubles
+)
+
+// setSmallMode trades speed for memory like bzip2 -s: one worker, at
+// most one block in flight across the whole run, small copy buffers
+// and a garbage collector that keeps the heap close to the live set
+func setSmallMode() {
+	*cores = 1
+	setMemoryLimit(0, *level)
+	debug.SetGCPercent(smallGCPercent)
+}
+
+// copyData copies src to dst like io.Copy, with a smaller buffer
+// under -s
+func copyData(dst io.Writer, src io.Reader) (int64, error) {
+	if !*small {
+		return io.Copy(dst, src)
+	}
+	return io.CopyBuffer(dst, src, make([]byte, smallCopyBuffer))
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// sparseBlock is the unit in which --sparse looks for zeros: aligned
+// blocks of this size that are all zero become holes
+const sparseBlock = 4096
+
+var zeroBlock = make([]byte, sparseBlock)
+
+// sparseWriter writes to a new file, seeking over aligned zero blocks
+// instead of writing them so that the filesystem leaves holes there
+type sparseWriter struct {
+	f    *os.File
+	pos  int64 // offset of the next byte written
+	hole int64 // bytes skipped since the last write
+}
+
+func (s *sparseWriter) Write(p []byte) (int, error) {
+	start := 0 // first byte of p not written yet
+	for i := 0; i < len(p); {
+		n := sparseBlock - int((s.pos+int64(i))%sparseBlock)
+		if n > len(p)-i {
+			n = len(p) - i
+		}
+		if n == sparseBlock && bytes.Equal(p[i:i+n], zeroBlock) {
+			if err := s.write(p[start:i]); err != nil {
+				return start, err
+			}
+			s.hole += int64(n)
+			start = i + n
+		}
+		i += n
+	}
+	if err := s.write(p[start:]); err != nil {
+		return start, err
+	}
+	s.pos += int64(len(p))
+	return len(p), nil
+}
+
+// write writes b after the pending hole, if any
+func (s *sparseWriter) write(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	if s.hole > 0 {
+		if _, err := s.f.Seek(s.hole, io.SeekCurrent); err != nil {
+			return err
+		}
+		s.hole = 0
+	}
+	_, err := s.f.Write(b)
+	return err
+}
+
+// Flush extends the file over a trailing hole, which no write follows
+func (s *sparseWriter) Flush() error {
+	if s.hole == 0 {
+		return nil
+	}
+	s.hole = 0
+	return s.f.Truncate(s.pos)
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pedroalbanese/bzip2/internal/fileio"
+)
+
+// --split SIZE cuts the compressed output into volumes FILE.bz2.001,
+// FILE.bz2.002... of at most SIZE bytes, for media or uploads with a
+// size limit. Volumes only end between the streams compressParallel
+// writes, one per block, so each is a valid bzip2 file of its own and
+// together they hold the same data as the unsplit file. Given the
+// first volume, -d decompresses them all in order into FILE and, as
+// they are its original, removes them all unless -k; later volumes
+// given as FILEs are left to the first
+
+// splitSize is the most a volume may hold, 0 for no splitting
+var splitSize sizeValue
+
+// minSplit is the least --split accepts: the smallest stream must fit
+// in a volume, as its writes can't be split
+const minSplit = 1 << 10
+
+// volumeDigits is the width of volume numbers
+const volumeDigits = 3
+
+// volumeName returns the name of volume n of the compressed file name
+func volumeName(name string, n int) string {
+	return fmt.Sprintf("%s.%0*d", name, volumeDigits, n)
+}
+
+// volumeOf returns the compressed file the volume name belongs to and
+// its number, or 0 if name isn't one
+func volumeOf(name string) (string, int) {
+	i := len(name) - volumeDigits - 1
+	if i <= 0 || name[i] != '.' {
+		return "", 0
+	}
+	for _, c := range name[i+1:] {
+		if c < '0' || c > '9' {
+			return "", 0
+		}
+	}
+	n, _ := strconv.Atoi(name[i+1:])
+	if from, _ := matchSuffix(filepath.Base(name[:i])); from == "" || n == 0 {
+		return "", 0
+	}
+	return name[:i], n
+}
+
+// restOfVolumes returns the volumes that follow the first, the name
+// of the first volume, as found on disk
+func restOfVolumes(first string) []string {
+	var names []string
+	whole, _ := volumeOf(first)
+	for n := 2; ; n++ {
+		v := volumeName(whole, n)
+		if _, err := os.Lstat(v); err != nil {
+			return names
+		}
+		names = append(names, v)
+	}
+}
+
+// dropLaterVolumes removes from files the volumes whose first volume
+// is there too, as decompressing it handles them
+func dropLaterVolumes(files []string) []string {
+	given := make(map[string]bool)
+	for _, f := range files {
+		given[f] = true
+	}
+	kept := files[:0]
+	for _, f := range files {
+		if whole, n := volumeOf(f); n > 1 && given[volumeName(whole, 1)] {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+// volumeWriter writes the volumes of name, starting a new one
+// whenever a write wouldn't fit in the current one. Each write must
+// be a whole stream
+type volumeWriter struct {
+	name  string   // compressed file
+	temps []string // temporary names of the volumes started
+	f     *os.File // volume being written, if any
+	w     io.Writer
+	n     int64 // bytes in the current volume
+}
+
+func (v *volumeWriter) Write(p []byte) (int, error) {
+	if v.f != nil && v.n+int64(len(p)) > int64(splitSize) {
+		if err := v.finish(); err != nil {
+			return 0, err
+		}
+	}
+	if v.f == nil {
+		name := fileio.TempName(volumeName(v.name, len(v.temps)+1))
+		f, w, err := createOutput(name)
+		if err != nil {
+			return 0, err
+		}
+		v.temps = append(v.temps, name)
+		v.f, v.w, v.n = f, w, 0
+		if int64(len(p)) > int64(splitSize) {
+			warn("%s: a block takes %d bytes, more than the volume size (lower the level)\n",
+				volumeName(v.name, len(v.temps)), len(p))
+		}
+	}
+	n, err := v.w.Write(p)
+	v.n += int64(n)
+	return n, err
+}
+
+// finish completes the current volume
+func (v *volumeWriter) finish() error {
+	if v.f == nil {
+		return nil
+	}
+	f := v.f
+	v.f = nil
+	if err := closeOutput(f, v.w); err != nil {
+		return err
+	}
+	return fileio.CheckSize(v.temps[len(v.temps)-1], v.n)
+}
+
+// abort removes the volumes not renamed into place
+func (v *volumeWriter) abort() {
+	if v.f != nil {
+		v.f.Close()
+	}
+	for _, t := range v.temps {
+		if t != "" {
+			os.Remove(t)
+		}
+	}
+}
+
+// splitFile compresses inFilePath into the volumes of outFilePath;
+// inInfo and isLink are as in processFile
+func splitFile(inFilePath string, inInfo os.FileInfo, isLink bool, outFilePath string) error {
+	first := volumeName(outFilePath, 1)
+	if f, err := os.Lstat(first); err == nil {
+		if f.IsDir() {
+			return fmt.Errorf("outFile %s is a directory", first)
+		}
+		if !*force && !confirmOverwrite(inFilePath, first) {
+			return fmt.Errorf("outFile %s exists. use -f to overwrite", first)
+		}
+	}
+	if *dryRun {
+		fmt.Fprint(os.Stdout, safeText(fmt.Sprintf("would compress %s to volumes %s...\n",
+			inFilePath, first)))
+		return nil
+	}
+
+	inFile, in, err := openInput(inFilePath)
+	if err != nil {
+		return err
+	}
+	defer inFile.Close()
+	v := &volumeWriter{name: outFilePath}
+	defer v.abort()
+	src, err := startPreFilter(guardReader(in))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	nin, nout, err := compressParallel(v, src, *level, *cores)
+	if err == nil {
+		err = v.finish()
+	}
+	if err != nil {
+		return err
+	}
+	tracef(verboseFiles, "%s: %d in, %d out, in %d volumes.\n", inFilePath, nin, nout, len(v.temps))
+
+	for _, t := range v.temps {
+		if err := preserveMeta(inFilePath, inInfo, t); err != nil {
+			return err
+		}
+	}
+	for i, t := range v.temps {
+		if err := os.Rename(t, volumeName(outFilePath, i+1)); err != nil {
+			return err
+		}
+		v.temps[i] = ""
+	}
+	// Volumes left over from a longer split would be read as part of
+	// this one
+	for n := len(v.temps) + 1; os.Remove(volumeName(outFilePath, n)) == nil; n++ {
+	}
+
+	if !*keep {
+		if isLink && !*force {
+			warn("%s: keeping symbolic link (use -f to remove it)\n", inFilePath)
+			return nil
+		}
+		if err := fileio.SyncDir(filepath.Dir(outFilePath)); err != nil {
+			return err
+		}
+		return removeOriginal(inFilePath)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/pedroalbanese/bzip2/pkg/bz"
+)
+
+// streamMagic starts every bzip2 stream, followed by the level digit
+var streamMagic = []byte("BZh")
+
+// testParallel verifies the integrity of a size-byte compressed file
+// with bz.TestAt, decoding its blocks concurrently with up to workers
+// goroutines
+func testParallel(f io.ReaderAt, size int64, workers int) error {
+	return bz.TestAt(f, size, engineOptions(*level, workers))
+}
+
+// streamTo writes the decompressed name ("-" for stdin) to w. Regular
+// files are decoded in parallel where their structure allows
+func streamTo(w io.Writer, name string) error {
+	var in io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() && *cores > 1 && !*small {
+			_, err = decodeParallel(f, fi.Size(), w, *cores)
+			if err != errNotSplittable {
+				return err
+			}
+		}
+		in = f
+	}
+	z, err := bz.NewReader(in)
+	if err != nil {
+		return err
+	}
+	defer z.Close()
+	if _, err := copyData(w, z); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+// streamInfo describes a stream found by scanStreams
+type streamInfo struct {
+	start  int64   // byte offset of the header
+	level  byte    // block size in units of 100k
+	blocks []int64 // bit offset of each block magic
+	footer int64   // bit offset of the footer magic, -1 if missing
+	crc    uint32  // combined CRC stored in the footer
+}
+
+// scanStreams locates the streams in the first size bytes of f, using
+// the magics found by bz.ScanMarks. A stream ends at the first footer
+// magic followed by the end of the file or by another stream header;
+// a block magic found by chance inside compressed data is listed as a
+// block of its own. Without such a footer magic, the first one met
+// ends the stream. It also returns the byte offset where the streams
+// stop, before size if the file has trailing garbage or the last
+// stream has no footer
+func scanStreams(f io.ReaderAt, size int64) ([]streamInfo, int64, error) {
+	marks, err := bz.ScanMarks(f, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	level := func(pos int64) (byte, bool) {
+		hdr := make([]byte, 4)
+		if _, err := f.ReadAt(hdr, pos); err != nil ||
+			!bytes.HasPrefix(hdr, streamMagic) || hdr[3] < '1' || hdr[3] > '9' {
+			return 0, false
+		}
+		return hdr[3] - '0', true
+	}
+
+	var streams []streamInfo
+	pos := int64(0)
+	k := 0
+	for pos < size {
+		l, ok := level(pos)
+		if !ok {
+			break
+		}
+		s := streamInfo{start: pos, level: l, footer: -1}
+		next := int64(-1)
+		j := k     // mark reached when a footer magic was first met
+		first := 0 // blocks before that footer magic
+		for ; k < len(marks) && next < 0; k++ {
+			m := marks[k]
+			if m.Bit < (pos+4)*8 {
+				continue
+			}
+			if !m.End {
+				s.blocks = append(s.blocks, m.Bit)
+				continue
+			}
+			if s.footer < 0 {
+				s.footer, j, first = m.Bit, k, len(s.blocks)
+			}
+			end := (m.Bit + 80 + 7) / 8
+			if _, ok := level(end); end == size || ok {
+				s.footer, next = m.Bit, end
+			}
+		}
+		if next < 0 && s.footer >= 0 {
+			// Garbage follows: the stream ends at the first footer
+			s.blocks = s.blocks[:first]
+			next, k = (s.footer+80+7)/8, j+1
+		}
+		if next < 0 {
+			streams = append(streams, s)
+			break
+		}
+		b, err := bz.ReadBits(f, s.footer+48, s.footer+80)
+		if err != nil {
+			return nil, 0, err
+		}
+		s.crc = binary.BigEndian.Uint32(b)
+		streams = append(streams, s)
+		pos = next
+	}
+	return streams, pos, nil
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A subcommand is a mode with arguments of its own, named by the
+// first argument, as in bzip2 grep PATTERN FILE... They replace the
+// shell scripts shipped with bzip2. A file named like a subcommand is
+// compressed by giving it as ./grep, or after --
+
+// subcommand is one of the modes given as first argument
+type subcommand struct {
+	name string
+	args string // synopsis of the arguments
+	desc string
+	run  func(args []string) int // returns the exit status
+}
+
+// watch takes the options of bzip2 itself, so it has no run function:
+// main parses them as usual and then watches the directories given
+
+// subcommands lists the subcommands in the order of the usage message
+var subcommands = []subcommand{
+	{"grep", "[GREP-OPTION]... PATTERN [FILE]...", "search compressed FILEs with grep, or with -r all the files under them", runGrep},
+	{"diff", "[DIFF-OPTION]... FILE1 [FILE2]", "compare the contents of (compressed) files with diff", runDiff},
+	{"cmp", "[CMP-OPTION]... FILE1 [FILE2]", "compare the contents of (compressed) files with cmp", runCmp},
+	{"verify", "FILE [FILE.bz2]", "check that FILE.bz2 decompresses to the contents of FILE", runVerify},
+	{"inspect", "FILE...", "dump the streams, blocks and CRCs of compressed FILEs", runInspect},
+	{"manifest", "FILE...", "list the blocks of compressed FILEs, with their offsets and CRCs, as JSON", runManifest},
+	{"compare-levels", "[--sample SIZE | --full] FILE", "compress (the start of) FILE at each level and compare sizes and times", runCompareLevels},
+	{"fix-names", "[-n] [-r] PATH...", "add .bz2 to the names of compressed files lacking it, and strip it from files that aren't", runFixNames},
+	{"recompress", "[-LEVEL] [-f] [-v] FILE...", "compress FILEs again at LEVEL (9 by default), replacing them in place", runRecompress},
+	{"join", "PART... -o WHOLE", "check compressed PARTs and concatenate them into WHOLE", runJoin},
+	{"serve", "[--listen ADDR] [--max-requests N]", "compress and decompress POSTed data over HTTP, at /compress and /decompress", runServe},
+	{"watch", "[OPTION]... DIR...", "compress the files written to DIRs once they settle, until interrupted", nil},
+}
+
+// lookupSubcommand returns the subcommand called name, or nil
+func lookupSubcommand(name string) *subcommand {
+	for i := range subcommands {
+		if subcommands[i].name == name {
+			return &subcommands[i]
+		}
+	}
+	return nil
+}
+
+// subcommandUsage lists the subcommands for the usage message
+func subcommandUsage(w io.Writer, prog string) {
+	fmt.Fprintf(w, "\nSubcommands, given before anything else:\n")
+	for _, s := range subcommands {
+		fmt.Fprintf(w, "  %s %s %s\n    \t%s\n", prog, s.name, s.args, s.desc)
+	}
+}
+
+// optionArg returns the option of a that may take an argument, the
+// last of a bundle of short options like -iA3, and whether the
+// argument is attached; withArg lists the options that take one
+func optionArg(a string, withArg map[string]bool) (name string, attached bool) {
+	if strings.HasPrefix(a, "--") {
+		if eq := strings.IndexByte(a, '='); eq >= 0 {
+			return a[:eq], true
+		}
+		return a, false
+	}
+	for j := 1; j < len(a); j++ {
+		if o := "-" + a[j:j+1]; withArg[o] {
+			return o, j+1 < len(a)
+		}
+	}
+	return "", false
+}
+
+// splitOptions separates the leading options of args, meant for
+// another program, from its operands
+func splitOptions(args []string, withArg map[string]bool) (opts, operands []string, err error) {
+	i := 0
+	for ; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			return append(opts, a), args[i+1:], nil
+		}
+		if len(a) < 2 || a[0] != '-' {
+			break
+		}
+		opts = append(opts, a)
+		if name, attached := optionArg(a, withArg); withArg[name] && !attached {
+			if i+1 == len(args) {
+				return nil, nil, fmt.Errorf("option %s requires an argument", a)
+			}
+			i++
+			opts = append(opts, args[i])
+		}
+	}
+	return opts, args[i:], nil
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// words splits s at spaces, an empty s giving none
+func words(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, " ")
+}
+
+// sameWords reports whether a holds the words of s, nil and empty
+// alike
+func sameWords(a []string, s string) bool {
+	return len(a) == 0 && s == "" || reflect.DeepEqual(a, words(s))
+}
+
+func TestSplitOptions(t *testing.T) {
+	tests := []struct {
+		args           string
+		withArg        map[string]bool
+		opts, operands string
+		err            bool
+	}{
+		{"a b", diffArgOpts, "", "a b", false},
+		{"-u a b", diffArgOpts, "-u", "a b", false},
+		{"-i a b", diffArgOpts, "-i", "a b", false}, // a flag of diff
+		{"-i 10 a b", cmpArgOpts, "-i 10", "a b", false},
+		{"-n 5 -l a b", cmpArgOpts, "-n 5 -l", "a b", false},
+		{"-n5 a b", cmpArgOpts, "-n5", "a b", false},
+		{"-U 3 -L x a b", diffArgOpts, "-U 3 -L x", "a b", false},
+		{"-wU3 a b", diffArgOpts, "-wU3", "a b", false},
+		{"-wU 3 a b", diffArgOpts, "-wU 3", "a b", false},
+		{"--label=x --width 80 a b", diffArgOpts, "--label=x --width 80", "a b", false},
+		{"-- -a b", diffArgOpts, "--", "-a b", false},
+		{"- b", diffArgOpts, "", "- b", false},
+		{"-U", diffArgOpts, "", "", true},
+		{"-u --bytes", cmpArgOpts, "", "", true},
+	}
+	for _, tt := range tests {
+		opts, operands, err := splitOptions(words(tt.args), tt.withArg)
+		if (err != nil) != tt.err {
+			t.Errorf("splitOptions(%q): error %v", tt.args, err)
+			continue
+		}
+		if !sameWords(opts, tt.opts) || !sameWords(operands, tt.operands) {
+			t.Errorf("splitOptions(%q) = %q, %q, want %q, %q",
+				tt.args, opts, operands, words(tt.opts), words(tt.operands))
+		}
+	}
+}
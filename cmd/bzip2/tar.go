@@ -0,0 +1,186 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pedroalbanese/bzip2/internal/fileio"
+)
+
+// --tar archives each FILE, usually a directory, into FILE.tar.bz2 in
+// a single pass: a goroutine writes the tar stream into a pipe read by
+// the parallel compressor, so nothing is staged on disk. File names in
+// the archive start with the base name of FILE, as with tar -cjf
+// FILE.tar.bz2 FILE run from its parent; --exclude and --include apply
+// as with -r. The FILEs themselves are left in place
+
+// tarFile archives root into root.tar.bz2, or to stdout with -c
+func tarFile(root string) (err error) {
+	defer recoverInternal(&err)
+
+	// The archive of . is named after the directory, next to it
+	root = filepath.Clean(root)
+	if b := filepath.Base(root); b == "." || b == ".." {
+		if root, err = filepath.Abs(root); err != nil {
+			return err
+		}
+	}
+	if filepath.Dir(root) == root {
+		return fmt.Errorf("can't archive the root directory")
+	}
+	outFilePath := root + ".tar." + suffixes.first()
+	if *sfx {
+		outFilePath += sfxSuffix
+	}
+	if *outputDir != "" {
+		if outFilePath, err = relocate(outFilePath, filepath.Dir(root)); err != nil {
+			return err
+		}
+	}
+	if *stdout {
+		if !*force && isTerminal(os.Stdout) {
+			return fmt.Errorf("compressed data not written to a terminal (use -f to force)")
+		}
+	} else if _, err := os.Lstat(outFilePath); err == nil && !*force &&
+		!confirmOverwrite(root, outFilePath) {
+		return fmt.Errorf("outFile %s exists. use -f to overwrite", outFilePath)
+	}
+	if *dryRun {
+		dst := outFilePath
+		if *stdout {
+			dst = "standard output"
+		}
+		fmt.Fprint(os.Stdout, safeText(fmt.Sprintf("would archive %s to %s\n", root, dst)))
+		return nil
+	}
+
+	var out io.Writer = os.Stdout
+	outFile := os.Stdout
+	finalPath := outFilePath
+	if !*stdout {
+		outFilePath = fileio.TempName(finalPath)
+		defer func() {
+			if outFilePath != finalPath { // not renamed into place
+				os.Remove(outFilePath)
+			}
+		}()
+		if outFile, out, err = createOutput(outFilePath); err != nil {
+			return err
+		}
+		defer outFile.Close()
+	}
+
+	var stub int64
+	if *sfx {
+		if stub, err = writeStub(out, filepath.Base(root), 0, true); err != nil {
+			return err
+		}
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(catchInternal(func() error { return writeTar(pw, root) }))
+	}()
+	src, err := startPreFilter(guardReader(pr))
+	if err != nil {
+		pr.CloseWithError(err)
+		return err
+	}
+	defer src.Close()
+	nin, nout, err := compressParallel(out, src, *level, *cores)
+	pr.CloseWithError(err) // stops the archiver if compression failed
+	if err == nil {
+		err = closeOutput(outFile, out)
+	}
+	if err != nil {
+		return err
+	}
+	tracef(verboseFiles, "%s: archived, %d in, %d out.\n", root, nin, nout)
+
+	if !*stdout {
+		if err := fileio.CheckSize(outFilePath, stub+nout); err != nil {
+			return err
+		}
+		if err := fileio.DefaultMode(outFilePath); err != nil {
+			return err
+		}
+		if *sfx {
+			if err := makeExecutable(outFilePath); err != nil {
+				return err
+			}
+		}
+		if err := os.Rename(outFilePath, finalPath); err != nil {
+			return err
+		}
+		outFilePath = finalPath
+	}
+	return nil
+}
+
+// writeTar writes the tar archive of root to w
+func writeTar(w io.Writer, root string) error {
+	tw := tar.NewWriter(w)
+	parent := filepath.Dir(root)
+	err := walkTree(root, *followLink, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(parent, path)
+		if err != nil {
+			return err
+		}
+		if sub, _ := filepath.Rel(root, path); sub != "." && skipPath(sub, fi.IsDir()) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		link := ""
+		switch {
+		case fi.Mode()&os.ModeSymlink != 0:
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		case fi.Mode()&specialMode != 0:
+			warn("%s: skipping special file\n", path)
+			return nil
+		}
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if fi.IsDir() && !strings.HasSuffix(hdr.Name, "/") {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		// The size is in the header already: a file changed while
+		// being read would corrupt the archive
+		if _, err := io.CopyN(tw, f, fi.Size()); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
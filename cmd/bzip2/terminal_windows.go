@@ -0,0 +1,23 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// isTerminal reports whether f is a console. Go does no CRLF or text
+// mode translation on Windows, so pipes and files already carry binary
+// data unchanged; only a console converts what goes through it, which
+// is why compressed data must not be read from or written to one
+func isTerminal(f *os.File) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(f.Fd()), &mode) == nil
+}
@@ -0,0 +1,223 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pedroalbanese/bzip2/internal/fileio"
+)
+
+// --untar extracts each FILE.tar.bz2 into the current directory, or
+// the -C one, decompressing straight into the tar reader. Modes and
+// times are restored, and ownership too when run as root. Entries are
+// confined to the destination: leading slashes are dropped, names
+// with .. components are skipped, and nothing is written through a
+// symbolic link, whether it was there before or came from the archive
+
+// errEntries is returned when some entries could not be extracted
+var errEntries = errors.New("some entries were not extracted")
+
+// dirTimes is a directory whose mode and times are set once its
+// entries are extracted, as writing them would change its times and
+// a read-only mode would forbid it
+type dirTimes struct {
+	path string
+	hdr  *tar.Header
+}
+
+// untarFile extracts the compressed archive name ("-" for stdin)
+func untarFile(name string) (err error) {
+	defer recoverInternal(&err)
+
+	dest := *outputDir
+	if dest == "" {
+		dest = "."
+	}
+	if !*dryRun {
+		if err := os.MkdirAll(dest, 0777); err != nil {
+			return err
+		}
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(catchInternal(func() error { return streamTo(pw, name) }))
+	}()
+	defer pr.Close() // stops decompression if extraction fails
+
+	tr := tar.NewReader(guardReader(pr))
+	failed := false
+	var dirs []dirTimes
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := extractEntry(tr, hdr, dest, &dirs); err != nil {
+			warn("%s: %s: %v\n", name, hdr.Name, err)
+			failed = true
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- { // children first
+		if err := setEntryMeta(dirs[i].path, dirs[i].hdr); err != nil {
+			warn("%s: %s: %v\n", name, dirs[i].hdr.Name, err)
+			failed = true
+		}
+	}
+	if failed {
+		return errEntries
+	}
+	return nil
+}
+
+// extractEntry extracts the entry hdr, whose data tr holds, under dest
+func extractEntry(tr io.Reader, hdr *tar.Header, dest string, dirs *[]dirTimes) error {
+	rel, err := entryPath(hdr.Name)
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+	target := filepath.Join(dest, rel)
+	if err := checkParents(dest, rel); err != nil {
+		return err
+	}
+	if *dryRun {
+		fmt.Fprint(os.Stdout, safeText(fmt.Sprintf("would extract %s\n", target)))
+		return nil
+	}
+	tracef(verboseFiles, "%s\n", target)
+
+	// an existing directory is kept for a directory entry; anything
+	// else there, a symbolic link included, is replaced only with -f
+	if fi, err := os.Lstat(target); err == nil {
+		switch {
+		case fi.IsDir():
+			if hdr.Typeflag != tar.TypeDir {
+				return fmt.Errorf("%s is a directory", target)
+			}
+		case !*force:
+			return fmt.Errorf("%s exists. use -f to overwrite", target)
+		default:
+			if err := os.Remove(target); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		if err := os.Mkdir(target, 0700); err != nil && !os.IsExist(err) {
+			return err
+		}
+		*dirs = append(*dirs, dirTimes{target, hdr})
+		return nil
+	case tar.TypeReg, tar.TypeRegA:
+		f, err := fileio.Create(target)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, tr)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	case tar.TypeSymlink:
+		return os.Symlink(hdr.Linkname, target)
+	case tar.TypeLink:
+		src, err := entryPath(hdr.Linkname)
+		if err != nil {
+			return err
+		}
+		if err := checkParents(dest, src); err != nil {
+			return err
+		}
+		return os.Link(filepath.Join(dest, src), target)
+	default:
+		warn("%s: skipping special file\n", target)
+		return nil
+	}
+	return setEntryMeta(target, hdr)
+}
+
+// entryPath returns the name of an entry as a relative path, refusing
+// those that would climb out of the destination
+func entryPath(name string) (string, error) {
+	p := filepath.Clean(filepath.FromSlash(strings.TrimLeft(name, "/")))
+	if p == ".." || strings.HasPrefix(p, ".."+string(filepath.Separator)) || filepath.IsAbs(p) {
+		return "", fmt.Errorf("leaves the destination, skipped")
+	}
+	return p, nil
+}
+
+// checkParents makes sure none of the directories leading to rel
+// under dest is a symbolic link, which could point anywhere
+func checkParents(dest, rel string) error {
+	dir := dest
+	parts := strings.Split(filepath.Dir(rel), string(filepath.Separator))
+	for _, part := range parts {
+		if part == "." {
+			continue
+		}
+		dir = filepath.Join(dir, part)
+		fi, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			if *dryRun {
+				return nil
+			}
+			return os.MkdirAll(filepath.Join(dest, filepath.Dir(rel)), 0777)
+		}
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("goes through the symbolic link %s, skipped", dir)
+		}
+		if !fi.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+	}
+	return nil
+}
+
+// setEntryMeta restores the mode, times and, as root, the ownership
+// recorded in hdr on path. Chmod and Chtimes follow symbolic links, so
+// a path that became one since it was extracted is left alone
+func setEntryMeta(path string, hdr *tar.Header) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%s is now a symbolic link, skipped", path)
+	}
+	if os.Geteuid() == 0 {
+		if err := os.Lchown(path, hdr.Uid, hdr.Gid); err != nil {
+			warn("%s: can't preserve ownership: %v\n", path, err)
+		}
+	}
+	if err := os.Chmod(path, hdr.FileInfo().Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+		return err
+	}
+	atime := hdr.AccessTime
+	if atime.IsZero() {
+		atime = time.Now()
+	}
+	return os.Chtimes(path, atime, hdr.ModTime)
+}
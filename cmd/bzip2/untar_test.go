@@ -0,0 +1,114 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pedroalbanese/bzip2/pkg/bz"
+)
+
+func TestEntryPath(t *testing.T) {
+	tests := []struct {
+		name, want string
+		err        bool
+	}{
+		{"a", "a", false},
+		{"a/b/", filepath.FromSlash("a/b"), false},
+		{"./a", "a", false},
+		{"/etc/passwd", filepath.FromSlash("etc/passwd"), false},
+		{"a/../b", "b", false},
+		{"..", "", true},
+		{"../a", "", true},
+		{"a/../../b", "", true},
+		{"/../a", "", true},
+	}
+	for _, tt := range tests {
+		got, err := entryPath(tt.name)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("entryPath(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+// writeTarBz2 writes the entries hdrs, regular files holding their
+// names, as a compressed archive name
+func writeTarBz2(t *testing.T, name string, hdrs []*tar.Header) {
+	t.Helper()
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	for _, hdr := range hdrs {
+		var data []byte
+		if hdr.Typeflag == tar.TypeReg {
+			data = []byte(hdr.Name)
+			hdr.Size = int64(len(data))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(data)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, _, err := bz.Compress(f, &b, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// An archive can't reach outside the destination, by .. or through
+// a symbolic link it makes, not even to set the mode and times of an
+// existing directory
+func TestUntarConfined(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "dest")
+	outside := filepath.Join(dir, "outside")
+	if err := os.Mkdir(outside, 0700); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.Stat(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeTarBz2(t, filepath.Join(dir, "evil.tar.bz2"), []*tar.Header{
+		{Name: "ok", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "../escaped", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "x", Typeflag: tar.TypeSymlink, Linkname: outside},
+		{Name: "x/", Typeflag: tar.TypeDir, Mode: 0777, ModTime: old},
+		{Name: "x/planted", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "h", Typeflag: tar.TypeLink, Linkname: "../../etc/passwd"},
+	})
+
+	_, stderr, status := runBzip2(t, dir, nil, "-k", "--untar", "-C", dest, "evil.tar.bz2")
+	if status == exitOK {
+		t.Errorf("--untar of a hostile archive succeeded")
+	}
+	if data, err := os.ReadFile(filepath.Join(dest, "ok")); err != nil || string(data) != "ok" {
+		t.Errorf("ok not extracted: %q, %v; %s", data, err, stderr)
+	}
+	for _, name := range []string{"escaped", "outside/planted", "dest/h"} {
+		if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+			t.Errorf("%s was extracted", name)
+		}
+	}
+	after, err := os.Stat(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Mode() != before.Mode() || !after.ModTime().Equal(before.ModTime()) {
+		t.Errorf("outside went from %v %v to %v %v", before.Mode(), before.ModTime(), after.Mode(), after.ModTime())
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// Verbosity levels, raised by repeating -v
+const (
+	verboseFiles  = 1 // per-file ratio
+	verboseBlocks = 2 // per-block stats and timings
+	verboseDebug  = 3 // pipeline and worker diagnostics
+)
+
+// countValue is a boolean flag that counts how many times it is given,
+// so that -vvv (or -v -v -v) means 3
+type countValue int
+
+func (c *countValue) String() string { return strconv.Itoa(int(*c)) }
+
+func (c *countValue) Set(s string) error {
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+		*c = countValue(n) // an explicit level, e.g. from a config file
+		return nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	if b {
+		*c++
+	} else {
+		*c = 0
+	}
+	return nil
+}
+
+func (c *countValue) IsBoolFlag() bool { return true }
+
+// traceMu keeps messages from concurrent files from interleaving
+var traceMu sync.Mutex
+
+// tracef prints a diagnostic to stderr if the verbosity is at least level
+func tracef(level int, format string, a ...interface{}) {
+	if int(verbose) < level {
+		return
+	}
+	traceMu.Lock()
+	fmt.Fprint(os.Stderr, safeText(fmt.Sprintf(format, a...)))
+	traceMu.Unlock()
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// bzip2 verify FILE FILE.bz2 decompresses FILE.bz2 and compares the
+// data with FILE as it comes, in constant memory, reporting the offset
+// of the first difference. Given FILE.bz2 alone, FILE is its name
+// without the suffix. The exit status is 0 if they match, 1 if they
+// differ and 2 on errors, as with cmp
+
+// errDiffer stops decompression at the first difference
+var errDiffer = errors.New("contents differ")
+
+// compareWriter compares what is written to it with the data of r
+type compareWriter struct {
+	r   io.Reader
+	buf []byte
+	off int64 // bytes matched so far
+	eof bool  // r ended first
+}
+
+func (c *compareWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := len(p)
+		if n > len(c.buf) {
+			n = len(c.buf)
+		}
+		m, err := io.ReadFull(c.r, c.buf[:n])
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return written, err
+		}
+		for i := 0; i < m; i++ {
+			if c.buf[i] != p[i] {
+				c.off += int64(i)
+				return written + i, errDiffer
+			}
+		}
+		c.off += int64(m)
+		written += m
+		if m < n {
+			c.eof = true
+			return written, errDiffer
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+func runVerify(args []string) int {
+	opts, files, err := splitOptions(args, nil)
+	if err == nil && len(opts) > 0 && opts[0] != "--" {
+		err = fmt.Errorf("unknown option %s", opts[0])
+	}
+	if err == nil && len(files) == 1 {
+		if from, to := matchSuffix(files[0]); from != "" {
+			files = []string{strings.TrimSuffix(files[0], from) + to, files[0]}
+		} else {
+			err = errors.New(files[0] + ": no suffix to strip, give the original FILE")
+		}
+	}
+	if err == nil && len(files) != 2 {
+		err = errors.New("FILE and FILE.bz2 expected")
+	}
+	if err != nil {
+		log.Printf("%s verify: %v", os.Args[0], err)
+		return 2
+	}
+
+	orig, comp := files[0], files[1]
+	f, err := os.Open(orig)
+	if err != nil {
+		log.Printf("%s: %v", orig, err)
+		return 2
+	}
+	defer f.Close()
+	c := &compareWriter{r: f, buf: make([]byte, stageBuffer)}
+	err = streamTo(c, comp)
+	if err == nil {
+		// The original must end there too
+		var n int
+		n, err = f.Read(c.buf[:1])
+		if n > 0 {
+			fmt.Fprint(os.Stdout, safeText(fmt.Sprintf("the data of %s ends at offset %d, before %s\n",
+				comp, c.off, orig)))
+			return 1
+		} else if err == io.EOF {
+			err = nil
+		}
+	}
+	switch {
+	case err == errDiffer && c.eof:
+		fmt.Fprint(os.Stdout, safeText(fmt.Sprintf("%s ends at offset %d, before the data of %s\n",
+			orig, c.off, comp)))
+		return 1
+	case err == errDiffer:
+		fmt.Fprint(os.Stdout, safeText(fmt.Sprintf("%s differs from %s at offset %d\n", comp, orig, c.off)))
+		return 1
+	case err != nil:
+		log.Printf("%s: %v", comp, err)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+	"strings"
+
+	"github.com/pedroalbanese/bzip2"
+)
+
+// version is the release of this tool; builds from a module-aware
+// go install report the module version instead
+var version = "devel"
+
+// backend is the module implementing the bzip2 format
+const backend = "github.com/dsnet/compress"
+
+// versions returns the version of this tool and of the backend, as
+// recorded in the binary's build information
+func versions() (tool, lib string) {
+	tool, lib = version, "unknown"
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		tool = v
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == backend {
+			lib = dep.Version
+			if dep.Replace != nil {
+				lib = dep.Replace.Version
+			}
+		}
+	}
+	return
+}
+
+// printVersion writes the -V output to w
+func printVersion(w io.Writer, name string) {
+	tool, lib := versions()
+	fmt.Fprintf(w, "%s, a block-sorting file compressor. Version %s.\n", name, tool)
+	fmt.Fprintf(w, "Using %s %s.\n", backend, lib)
+	fmt.Fprintf(w, "Distributed under the ISC license; see %s -L for details.\n", name)
+}
+
+// printLicense writes the -L output to w
+func printLicense(w io.Writer, name string) {
+	printVersion(w, name)
+	fmt.Fprintf(w, "\n%s\n", strings.TrimSpace(bzip2.License))
+}
@@ -0,0 +1,25 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pedroalbanese/bzip2"
+)
+
+// -L prints LICENSE.md as it is
+func TestPrintLicense(t *testing.T) {
+	var b bytes.Buffer
+	printLicense(&b, "bzip2")
+	if !strings.Contains(b.String(), strings.TrimSpace(bzip2.License)) {
+		t.Errorf("bzip2 -L doesn't print LICENSE.md:\n%s", b.String())
+	}
+	if !strings.HasPrefix(bzip2.License, "Copyright") {
+		t.Errorf("embedded license starts with %.20q", bzip2.License)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// specialMode covers the files -r skips: opening a FIFO would block
+// until a writer shows up, and devices and sockets hold no data to
+// compress
+const specialMode = os.ModeNamedPipe | os.ModeSocket | os.ModeDevice | os.ModeCharDevice | os.ModeIrregular
+
+// walkTree is filepath.Walk, except that with follow set, as by
+// --follow-symlinks, links to directories are descended into as well. A link leading back to
+// one of its own ancestors, which would make the walk loop forever, is
+// passed to fn as an error instead
+func walkTree(root string, follow bool, fn filepath.WalkFunc) error {
+	if !follow {
+		return filepath.Walk(root, fn)
+	}
+	fi, err := os.Stat(root)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+	err = walkFollow(root, fi, nil, fn)
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+// walkFollow walks name, whose (followed) information is fi, below
+// the directories in ancestors
+func walkFollow(name string, fi os.FileInfo, ancestors []os.FileInfo, fn filepath.WalkFunc) error {
+	if fi.IsDir() {
+		// Same device and inode as a directory we are in
+		for _, a := range ancestors {
+			if os.SameFile(a, fi) {
+				return fn(name, fi, fmt.Errorf("symbolic link loop, not descending"))
+			}
+		}
+	}
+	if err := fn(name, fi, nil); err != nil || !fi.IsDir() {
+		return err
+	}
+
+	d, err := os.Open(name)
+	if err != nil {
+		return fn(name, fi, err)
+	}
+	names, err := d.Readdirnames(-1)
+	d.Close()
+	if err != nil {
+		return fn(name, fi, err)
+	}
+	sort.Strings(names)
+
+	ancestors = append(ancestors, fi)
+	for _, n := range names {
+		path := filepath.Join(name, n)
+		cfi, err := os.Stat(path)
+		if err != nil {
+			err = fn(path, nil, err)
+		} else {
+			err = walkFollow(path, cfi, ancestors, fn)
+		}
+		if err == filepath.SkipDir && (cfi == nil || cfi.IsDir()) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pedroalbanese/bzip2/internal/fileio"
+)
+
+// bzip2 watch DIR... compresses the files written to the DIRs as they
+// are finished, in place of a cron job running find: each file is
+// compressed once no change was reported for --settle and its size
+// and modification time still match what they were then, which is the
+// best sign a writer is done that every system offers. Options are
+// those of bzip2, so -k, -C, --include, --exclude and the others
+// apply; -r also watches the directories below, including those
+// created later. Files already there when watching starts are left
+// alone, and so are those a run leaves behind: anything with a
+// compressed suffix, volumes, temporary outputs and backups.
+// SIGINT and SIGTERM stop watching once the file in hand is done.
+// Should the system drop events, as when too many arrive at once, the
+// watched directories are scanned again for files modified since
+// watching started
+
+// watchEvent is a change to an entry of a watched directory
+type watchEvent struct {
+	path     string
+	gone     bool // removed or renamed away
+	overflow bool // events were lost, path is unset
+}
+
+// pendingFile is a file waiting to settle
+type pendingFile struct {
+	root  string    // DIR it was found under
+	seen  time.Time // last change reported
+	size  int64
+	mtime time.Time
+}
+
+// watchDirs watches dirs until interrupted and returns the exit status
+func watchDirs(dirs []string) int {
+	if *stdout || *decompress || *test {
+		log.Printf("%s watch: only compresses files in place", os.Args[0])
+		return exitEnv
+	}
+	if len(dirs) == 0 {
+		log.Printf("%s watch: no DIR given", os.Args[0])
+		return exitEnv
+	}
+	if *settle <= 0 {
+		log.Printf("%s watch: --settle must be positive", os.Args[0])
+		return exitEnv
+	}
+	dw, err := newDirWatcher()
+	if err != nil {
+		log.Printf("%s watch: %v", os.Args[0], err)
+		return exitEnv
+	}
+	defer dw.close()
+
+	roots := make(map[string]string) // watched directory to its DIR
+	watch := func(dir, root string) error {
+		if _, ok := roots[dir]; ok {
+			return nil
+		}
+		if err := dw.add(dir); err != nil {
+			return err
+		}
+		roots[dir] = root
+		tracef(verboseFiles, "%s: watching\n", dir)
+		return nil
+	}
+	var tops []string // the DIRs
+	for _, d := range dirs {
+		d = filepath.Clean(d)
+		tops = append(tops, d)
+		fi, err := os.Stat(d)
+		if err == nil && !fi.IsDir() {
+			err = errors.New("not a directory")
+		}
+		if err == nil {
+			err = watchTree(d, d, watch, nil)
+		}
+		if err != nil {
+			log.Printf("%s: %v", d, err)
+			return exitEnv
+		}
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	tick := time.NewTicker(*settle / 4)
+	defer tick.Stop()
+	started := time.Now()
+	pending := make(map[string]*pendingFile)
+	handled := make(map[string]time.Time) // files queued, to their mtime then
+	queued := make(map[string]bool)       // waiting for or in the worker
+	found := func(path, root string, fi os.FileInfo) {
+		if fi.Mode().IsRegular() && !skipWatched(root, path, false) {
+			pending[path] = &pendingFile{root, time.Now(), fi.Size(), fi.ModTime()}
+		}
+	}
+
+	// A worker compresses the settled files, so events keep being
+	// read while it does: those fsnotify can't deliver pile up in the
+	// kernel, whose queue overflows
+	var queue []*watchJob
+	jobs := make(chan *watchJob)
+	results := make(chan *watchJob)
+	go func() {
+		defer close(results)
+		for j := range jobs {
+			j.err = processFile(j.path, filepath.Dir(j.root))
+			results <- j
+		}
+	}()
+	status := exitOK
+	report := func(j *watchJob) {
+		delete(queued, j.path)
+		if j.err != nil {
+			log.Printf("%s: %v", j.path, j.err)
+			status = worse(status, statusOf(j.err))
+		}
+	}
+	finish := func() int {
+		close(jobs) // the file in hand is finished, the queue dropped
+		for j := range results {
+			report(j)
+		}
+		return status
+	}
+
+	for {
+		var next chan<- *watchJob
+		if len(queue) > 0 {
+			next = jobs
+		}
+		select {
+		case ev, ok := <-dw.events:
+			if !ok {
+				return finish()
+			}
+			if ev.overflow {
+				// Changes were lost: look for what they would have
+				// reported, leaving what was there at the start
+				tracef(verboseFiles, "watch: events lost, rescanning\n")
+				for _, d := range tops {
+					if err := rescanTree(d, started, handled, watch, found); err != nil {
+						log.Printf("%s: %v", d, err)
+					}
+				}
+				continue
+			}
+			if ev.gone {
+				delete(pending, ev.path)
+				delete(handled, ev.path)
+				continue
+			}
+			root, ok := roots[filepath.Dir(ev.path)]
+			if !ok {
+				continue
+			}
+			fi, err := os.Lstat(ev.path)
+			switch {
+			case err != nil:
+				delete(pending, ev.path)
+			case fi.IsDir():
+				// Its files may have been written before it was watched
+				if *recursive && !skipWatched(root, ev.path, true) {
+					if err := watchTree(ev.path, root, watch, found); err != nil {
+						log.Printf("%s: %v", ev.path, err)
+					}
+				}
+			default:
+				found(ev.path, root, fi)
+			}
+		case now := <-tick.C:
+			for path, p := range pending {
+				if now.Sub(p.seen) < *settle || queued[path] {
+					continue
+				}
+				fi, err := os.Lstat(path)
+				if err != nil || !fi.Mode().IsRegular() {
+					delete(pending, path)
+					continue
+				}
+				if fi.Size() != p.size || !fi.ModTime().Equal(p.mtime) {
+					p.seen, p.size, p.mtime = now, fi.Size(), fi.ModTime()
+					continue
+				}
+				delete(pending, path)
+				handled[path], queued[path] = p.mtime, true
+				queue = append(queue, &watchJob{path: path, root: p.root})
+			}
+		case next <- queueHead(queue):
+			queue = queue[1:]
+		case j := <-results:
+			report(j)
+		case <-stop:
+			return finish()
+		}
+	}
+}
+
+// watchJob is a settled file handed to the worker, and its result
+type watchJob struct {
+	path, root string
+	err        error
+}
+
+// queueHead returns the first job of queue, or nil if it's empty
+func queueHead(queue []*watchJob) *watchJob {
+	if len(queue) == 0 {
+		return nil
+	}
+	return queue[0]
+}
+
+// rescanTree hands the files under the DIR root to found as if their
+// events had been seen, and watches the directories not watched yet.
+// Files modified before started, as those there when watching began,
+// and those handled since with the same mtime are left alone
+func rescanTree(root string, started time.Time, handled map[string]time.Time,
+	watch func(dir, root string) error, found func(path, root string, fi os.FileInfo)) error {
+	return walkTree(root, *followLink, func(path string, fi os.FileInfo, err error) error {
+		switch {
+		case err != nil:
+			return nil // gone already, its events tell
+		case fi.IsDir():
+			if path == root {
+				return nil
+			}
+			if !*recursive || skipWatched(root, path, true) {
+				return filepath.SkipDir
+			}
+			return watch(path, root)
+		case fi.ModTime().Before(started), handled[path].Equal(fi.ModTime()):
+			return nil
+		}
+		found(path, root, fi)
+		return nil
+	})
+}
+
+// watchTree watches dir, found under root, and with -r the
+// directories below it, handing the files in them to found if not nil
+func watchTree(dir, root string, watch func(dir, root string) error,
+	found func(path, root string, fi os.FileInfo)) error {
+	if !*recursive {
+		return watch(dir, root)
+	}
+	return walkTree(dir, *followLink, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			if found != nil {
+				found(path, root, fi)
+			}
+			return nil
+		}
+		if path != root && skipWatched(root, path, true) {
+			return filepath.SkipDir
+		}
+		return watch(path, root)
+	})
+}
+
+// skipWatched reports whether path, found under root, is to be left
+// alone
+func skipWatched(root, path string, isDir bool) bool {
+	if rel, err := filepath.Rel(root, path); err != nil || skipPath(rel, isDir) {
+		return true
+	}
+	if isDir {
+		return false
+	}
+	name := filepath.Base(path)
+	if from, _ := matchSuffix(strings.TrimSuffix(name, metaSuffix)); from != "" {
+		return true
+	}
+	if _, n := volumeOf(name); n > 0 {
+		return true
+	}
+	if fileio.IsTempName(name) || (*sfx && strings.HasSuffix(name, sfxSuffix)) {
+		return true
+	}
+	return backup != "" && strings.HasSuffix(name, "~")
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || windows
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris windows
+
+package main
+
+import (
+	"log"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// dirWatcher reports the changes to the entries of directories, with
+// fsnotify
+type dirWatcher struct {
+	w      *fsnotify.Watcher
+	events chan watchEvent
+}
+
+func newDirWatcher() (*dirWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	d := &dirWatcher{w: w, events: make(chan watchEvent)}
+	go func() {
+		defer close(d.events)
+		for {
+			select {
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				gone := ev.Op&(fsnotify.Remove|fsnotify.Rename) != 0
+				d.events <- watchEvent{path: ev.Name, gone: gone}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				if err == fsnotify.ErrEventOverflow {
+					d.events <- watchEvent{overflow: true}
+					continue
+				}
+				log.Printf("watch: %v", err)
+			}
+		}
+	}()
+	return d, nil
+}
+
+// add starts watching the entries of dir
+func (d *dirWatcher) add(dir string) error { return d.w.Add(dir) }
+
+func (d *dirWatcher) close() { d.w.Close() }
@@ -0,0 +1,24 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris,!windows
+
+package main
+
+import "errors"
+
+// dirWatcher is unavailable: fsnotify doesn't support this system
+type dirWatcher struct {
+	events chan watchEvent
+}
+
+func newDirWatcher() (*dirWatcher, error) {
+	return nil, errors.New("watching directories isn't supported on this system")
+}
+
+func (d *dirWatcher) add(dir string) error { return nil }
+
+func (d *dirWatcher) close() {}
@@ -1,10 +1,15 @@
 module github.com/pedroalbanese/bzip2
 
-go 1.12
+go 1.21
 
 require (
 	github.com/dsnet/compress v0.0.1
+	github.com/fsnotify/fsnotify v1.4.9
+	rsc.io/getopt v0.0.0-20170811000552-20be20937449
+)
+
+require (
 	golang.org/x/crypto v0.0.0-20210921155107-089bfa567519 // indirect
 	golang.org/x/net v0.0.0-20211029224645-99673261e6eb // indirect
-	rsc.io/getopt v0.0.0-20170811000552-20be20937449 // indirect
+	golang.org/x/sys v0.0.0-20210615035016-665e8c7367d1 // indirect
 )
@@ -0,0 +1,20 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build !linux && !dragonfly && !openbsd && !solaris && !darwin && !freebsd && !netbsd && !windows
+// +build !linux,!dragonfly,!openbsd,!solaris,!darwin,!freebsd,!netbsd,!windows
+
+package fileio
+
+import (
+	"os"
+	"time"
+)
+
+// Atime returns the access time of fi, which isn't known here:
+// the modification time stands in for it
+func Atime(fi os.FileInfo) time.Time {
+	return fi.ModTime()
+}
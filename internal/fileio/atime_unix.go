@@ -0,0 +1,23 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build linux || dragonfly || openbsd || solaris
+// +build linux dragonfly openbsd solaris
+
+package fileio
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Atime returns the access time of fi
+func Atime(fi os.FileInfo) time.Time {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec))
+	}
+	return fi.ModTime()
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+// Package fileio is how the bzip2 command and the file functions of
+// package bz write their outputs, so both do it the same way. An
+// output is created under a temporary name next to its final one,
+// refusing links and only open to its owner, and renamed into place
+// once complete and given the metadata of its input; the input is
+// only removed once the rename is on disk
+package fileio
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CreateFlags and CreateMode are what Create opens a file with, for
+// callers that have to add flags of their own
+const (
+	CreateFlags             = os.O_WRONLY | os.O_CREATE | os.O_EXCL | NoFollow
+	CreateMode  os.FileMode = 0600
+)
+
+// Create creates a new file for writing, failing if it exists or is a
+// symbolic link, so that a link planted in a shared directory can't
+// redirect the output. Only the owner may access the file until
+// PreserveMeta or DefaultMode sets its final mode, once it is closed
+func Create(name string) (*os.File, error) {
+	return os.OpenFile(name, CreateFlags, CreateMode)
+}
+
+// TempName returns the name the output for name is written under
+// until it is complete. It is in the same directory, so the rename
+// never crosses filesystems
+func TempName(name string) string {
+	return fmt.Sprintf("%s.tmp.%d", name, os.Getpid())
+}
+
+// IsTempName reports whether name is one TempName returns, in this
+// process or another
+func IsTempName(name string) bool {
+	i := strings.LastIndex(name, ".tmp.")
+	if i <= 0 {
+		return false
+	}
+	_, err := strconv.ParseUint(name[i+len(".tmp."):], 10, 32)
+	return err == nil
+}
+
+// CheckSize makes sure the closed output name holds all the size
+// bytes written to it, should the filesystem have lost any without
+// reporting an error
+func CheckSize(name string, size int64) error {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	if fi.Size() != size {
+		return fmt.Errorf("output %s is incomplete: %d of %d bytes written",
+			name, fi.Size(), size)
+	}
+	return nil
+}
+
+// SameFile reports whether name, through a link or a case-insensitive
+// name, is the file fi describes
+func SameFile(fi os.FileInfo, name string) bool {
+	o, err := os.Stat(name)
+	return err == nil && os.SameFile(fi, o)
+}
+
+// DefaultMode gives the closed output name the mode of a new file, for
+// outputs with no input to take it from
+func DefaultMode(name string) error {
+	return os.Chmod(name, 0666&^umask)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package fileio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsTempName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{TempName("a.bz2"), true},
+		{"a.bz2.tmp.123", true},
+		{"dir/a.tmp.1.tmp.77", true},
+		{"a.tmp.", false},
+		{"a.tmp.log", false},
+		{"a.tmp.12x", false},
+		{"a.tmp.-1", false},
+		{"a.tmp.99999999999", false},
+		{".tmp.123", false},
+		{"a.tmp", false},
+		{"app.tmp.log.bz2", false},
+	}
+	for _, tt := range tests {
+		if got := IsTempName(tt.name); got != tt.want {
+			t.Errorf("IsTempName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Create refuses existing files and links, and leaves new ones to their
+// owner
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "a")
+	f, err := Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := f.Stat()
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm()&^CreateMode != 0 {
+		t.Errorf("created with mode %#o", fi.Mode().Perm())
+	}
+	if _, err := Create(name); err == nil {
+		t.Errorf("Create of an existing file succeeded")
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(filepath.Join(dir, "missing"), link); err != nil {
+		t.Skip(err)
+	}
+	if _, err := Create(link); err == nil {
+		t.Errorf("Create through a dangling link succeeded")
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("Create made the target of a link")
+	}
+}
+
+func TestCheckSize(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "a")
+	if err := os.WriteFile(name, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckSize(name, 5); err != nil {
+		t.Error(err)
+	}
+	if err := CheckSize(name, 6); err == nil {
+		t.Error("CheckSize of a short file succeeded")
+	}
+}
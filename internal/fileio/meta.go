@@ -0,0 +1,94 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package fileio
+
+import (
+	"os"
+	"strings"
+)
+
+// MetaOptions control PreserveMeta; the zero value, or a nil
+// *MetaOptions, copies all it can and reports nothing
+type MetaOptions struct {
+	NoXattrs  bool // leave out extended attributes, as with --no-xattrs
+	NoContext bool // leave out the SELinux context, as with --no-context
+
+	// Warn is told of what couldn't be copied, Info of the failures
+	// expected of an unprivileged user
+	Warn, Info func(format string, a ...interface{})
+}
+
+func (o *MetaOptions) warn(format string, a ...interface{}) {
+	if o != nil && o.Warn != nil {
+		o.Warn(format, a...)
+	}
+}
+
+func (o *MetaOptions) info(format string, a ...interface{}) {
+	if o != nil && o.Info != nil {
+		o.Info(format, a...)
+	}
+}
+
+// PreserveMeta copies the metadata of the input in, as described by
+// fi, onto the finished out: ownership (only as root, who can give
+// files away), permissions and POSIX ACLs, extended attributes and the
+// SELinux context unless o leaves them out, and access and
+// modification times. The owner goes first, since changing it may
+// clear the set-id bits, and the attributes before a read-only mode
+// would forbid writing them. The access ACL comes after the mode,
+// which would otherwise rewrite its mask. It is only called once the
+// output is closed: until then the file keeps the owner-only mode
+// Create gave it, and a partial copy of a private file can't be read
+// by others
+func PreserveMeta(in string, fi os.FileInfo, out string, o *MetaOptions) error {
+	if uid, gid, ok := fileOwner(fi); ok && os.Geteuid() == 0 {
+		if err := os.Chown(out, uid, gid); err != nil {
+			// Like bzip2, carry on: the data is what matters
+			o.warn("%s: can't preserve ownership: %v\n", out, err)
+		}
+	}
+	if o == nil || !o.NoXattrs {
+		copyXattrs(in, out, plainXattr, o)
+	}
+	if o == nil || !o.NoContext {
+		// Without it, the file keeps the context inherited from its
+		// directory
+		copyXattrs(in, out, contextXattr, o)
+	}
+	if err := os.Chmod(out, fileMode(fi)); err != nil {
+		return err
+	}
+	copyXattrs(in, out, aclXattr, o)
+	return os.Chtimes(out, Atime(fi), fi.ModTime())
+}
+
+// fileMode returns the permission and set-id/sticky bits of fi
+func fileMode(fi os.FileInfo) os.FileMode {
+	return fi.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+}
+
+// plainXattr selects the extended attributes copied as such: those
+// with a meaning of their own, like ACLs, are left out
+func plainXattr(name string) bool {
+	switch {
+	case strings.HasPrefix(name, "user."), strings.HasPrefix(name, "trusted."):
+		return true
+	case strings.HasPrefix(name, "security."):
+		return name != "security.selinux"
+	}
+	return false
+}
+
+// aclXattr selects the attribute holding the access ACL on Linux
+func aclXattr(name string) bool {
+	return name == "system.posix_acl_access"
+}
+
+// contextXattr selects the attribute holding the SELinux context
+func contextXattr(name string) bool {
+	return name == "security.selinux"
+}
@@ -0,0 +1,14 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package fileio
+
+import "syscall"
+
+// NoFollow makes opening a symbolic link fail
+const NoFollow = syscall.O_NOFOLLOW
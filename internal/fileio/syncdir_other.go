@@ -0,0 +1,22 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build !windows
+// +build !windows
+
+package fileio
+
+import "os"
+
+// SyncDir flushes the directory dir to disk, making the creation and
+// renaming of the files in it durable
+func SyncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,15 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build windows
+// +build windows
+
+package fileio
+
+// SyncDir does nothing: directories can't be flushed on Windows, where
+// a rename is durable once the file data is
+func SyncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+package fileio
+
+import (
+	"bytes"
+	"strings"
+	"syscall"
+)
+
+// listXattrs returns the names of the extended attributes of path
+func listXattrs(path string) ([]string, error) {
+	size, err := syscall.Listxattr(path, nil)
+	for err == nil && size > 0 {
+		buf := make([]byte, size)
+		var n int
+		n, err = syscall.Listxattr(path, buf)
+		if err == syscall.ERANGE {
+			// Grew in between
+			size, err = syscall.Listxattr(path, nil)
+			continue
+		}
+		if err != nil {
+			break
+		}
+		var names []string
+		for _, name := range bytes.Split(buf[:n], []byte{0}) {
+			if len(name) > 0 {
+				names = append(names, string(name))
+			}
+		}
+		return names, nil
+	}
+	if err == syscall.ENOTSUP {
+		err = nil
+	}
+	return nil, err
+}
+
+// getXattr returns the value of the extended attribute name of path
+func getXattr(path, name string) ([]byte, error) {
+	for {
+		size, err := syscall.Getxattr(path, name, nil)
+		if err != nil || size == 0 {
+			return nil, err
+		}
+		buf := make([]byte, size)
+		n, err := syscall.Getxattr(path, name, buf)
+		if err != syscall.ERANGE {
+			return buf[:n], err
+		}
+	}
+}
+
+// copyXattrs copies the extended attributes selected by want from src
+// to dst. Failures are reported to o.Warn, the ones of namespaces that
+// need privileges only to o.Info, since an unprivileged user is
+// expected to be denied them
+func copyXattrs(src, dst string, want func(name string) bool, o *MetaOptions) {
+	names, err := listXattrs(src)
+	if err != nil {
+		o.warn("%s: can't list extended attributes: %v\n", src, err)
+		return
+	}
+	for _, name := range names {
+		if !want(name) {
+			continue
+		}
+		value, err := getXattr(src, name)
+		if err == nil {
+			err = syscall.Setxattr(dst, name, value, 0)
+		}
+		if err == nil {
+			continue
+		}
+		if !strings.HasPrefix(name, "user.") && (err == syscall.EPERM || err == syscall.ENOTSUP) {
+			o.info("%s: can't copy attribute %s: %v\n", dst, name, err)
+			continue
+		}
+		o.warn("%s: can't copy attribute %s: %v\n", dst, name, err)
+	}
+}
@@ -0,0 +1,13 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package fileio
+
+// copyXattrs copies extended attributes from src to dst; only Linux
+// is supported for now
+func copyXattrs(src, dst string, want func(name string) bool, o *MetaOptions) {}
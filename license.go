@@ -0,0 +1,16 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+// Package bzip2 holds what has to live at the root of the module: the
+// license text, which go:embed can't reach from any deeper directory.
+// The library is in pkg/bz and the command in cmd/bzip2
+package bzip2
+
+import _ "embed"
+
+// License is the text of LICENSE.md, as printed by bzip2 -L
+//
+//go:embed LICENSE.md
+var License string
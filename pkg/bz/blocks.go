@@ -0,0 +1,498 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/dsnet/compress/bzip2"
+)
+
+// Blocks inside a bzip2 stream are not byte-aligned, so a stream made
+// by stock bzip2 can't be split at byte boundaries like our own
+// multi-stream output. Instead the file is scanned bit by bit for the
+// 48-bit block and footer magics; the range between two consecutive
+// magics is a block candidate, which is turned into a one-block stream
+// of its own and handed to an ordinary decoder. A magic found by
+// chance inside compressed data only splits a real block in two, and
+// the halves fail to decode; they are then decoded merged again.
+
+const (
+	blockMagic48 = 0x314159265359
+	endMagic48   = 0x177245385090
+	magic48Mask  = 1<<48 - 1
+
+	// maxMerge is how many candidates a failed block may be merged
+	// with before it is reported as corrupt
+	maxMerge = 3
+)
+
+// ErrNotSplittable means a file doesn't have the structure needed to
+// decode its blocks independently; it has to be decoded serially,
+// which also produces the appropriate error if the file is damaged
+var ErrNotSplittable = errors.New("can't split file into blocks")
+
+// streamMagic starts every bzip2 stream, followed by the level digit
+var streamMagic = []byte("BZh")
+
+// A Mark is a block or footer magic found by ScanMarks
+type Mark struct {
+	Bit int64 // offset in bits from the start of the file
+	End bool  // footer (end of stream) rather than block magic
+}
+
+// magicTable maps every pair of bytes that can appear fully inside
+// a magic shifted by 0-7 bits to a bitmask of those shifts
+var magicTable = func() *[1 << 16]uint16 {
+	var t [1 << 16]uint16
+	for s := uint(0); s < 8; s++ {
+		for m, magic := range []uint64{blockMagic48, endMagic48} {
+			// Bits 8-s to 24-s of the magic fill the two bytes
+			// following the one where it starts
+			key := magic >> (48 - 24 + s) & 0xffff
+			t[key] |= 1 << (s*2 + uint(m))
+		}
+	}
+	return &t
+}()
+
+// ScanMarks returns the position of every block and footer magic in
+// the first size bytes of f, in order. Magics may also be found by
+// chance inside compressed data
+func ScanMarks(f io.ReaderAt, size int64) ([]Mark, error) {
+	var marks []Mark
+	buf := make([]byte, 1<<20+8)
+	var base int64 // file offset of buf[0]
+	for base < size {
+		n, err := f.ReadAt(buf[:len(buf)-8], base)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if n == 0 {
+			break
+		}
+		last := base+int64(n) >= size
+		limit := n - 8 // magics must start before here
+		if last {
+			// Nothing follows: pad so the loop can look ahead
+			for i := n; i < n+8; i++ {
+				buf[i] = 0
+			}
+			limit = n
+		}
+		marks = findMarks(marks, buf, 0, limit, base)
+		if last {
+			break
+		}
+		base += int64(limit)
+	}
+	return marks, nil
+}
+
+// findMarks appends to marks the magics starting in buf[from:limit],
+// where buf is at byte offset base of the file; 8 bytes must follow
+// limit
+func findMarks(marks []Mark, buf []byte, from, limit int, base int64) []Mark {
+	for i := from; i < limit; i++ {
+		hits := magicTable[uint16(buf[i+1])<<8|uint16(buf[i+2])]
+		if hits == 0 {
+			continue
+		}
+		v := binary.BigEndian.Uint64(buf[i : i+8])
+		for s := uint(0); s < 8; s++ {
+			if hits>>(s*2)&3 == 0 {
+				continue
+			}
+			switch v >> (16 - s) & magic48Mask {
+			case blockMagic48:
+				marks = append(marks, Mark{Bit: (base+int64(i))*8 + int64(s)})
+			case endMagic48:
+				marks = append(marks, Mark{Bit: (base+int64(i))*8 + int64(s), End: true})
+			}
+		}
+	}
+	return marks
+}
+
+// blockRun is a block candidate: the bits between a block magic and
+// the next magic
+type blockRun struct {
+	start, end int64  // bit range
+	crc        uint32 // block CRC as stored after the magic
+	last       bool   // last candidate of its stream
+	streamCRC  uint32 // stored combined CRC of the stream, if last
+}
+
+// ReadBits returns the bits [start, end) of f shifted to start at bit
+// 0 of the first byte; unused trailing bits are zero
+func ReadBits(f io.ReaderAt, start, end int64) ([]byte, error) {
+	first := start / 8
+	raw := make([]byte, (end+7)/8-first+1)
+	n, err := f.ReadAt(raw[:len(raw)-1], first)
+	if n < len(raw)-1 {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	nbits := end - start
+	shift := uint(start % 8)
+	out := make([]byte, (nbits+7)/8)
+	for i := range out {
+		out[i] = raw[i]<<shift | raw[i+1]>>(8-shift)
+	}
+	if rem := uint(nbits % 8); rem != 0 {
+		out[len(out)-1] &= 0xff << (8 - rem)
+	}
+	return out, nil
+}
+
+// A Layout is the block structure of a compressed file, as found by
+// ScanLayout: the block candidates of all its streams, in order
+type Layout struct {
+	runs []blockRun
+}
+
+// ScanLayout parses the structure of the size-byte file f: every
+// stream must start byte-aligned with a header right after the footer
+// of the previous one, and the file must end with the padding of a
+// footer. Otherwise it returns ErrNotSplittable
+func ScanLayout(f io.ReaderAt, size int64) (*Layout, error) {
+	runs, err := blockLayout(f, size)
+	if err != nil {
+		return nil, err
+	}
+	return &Layout{runs: runs}, nil
+}
+
+// blockLayout returns the block candidates of f, see ScanLayout
+func blockLayout(f io.ReaderAt, size int64) ([]blockRun, error) {
+	marks, err := ScanMarks(f, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []blockRun
+	hdr := make([]byte, 4)
+	pos := int64(0) // byte offset of the next stream header
+	k := 0          // next mark to consider
+	for pos < size {
+		if _, err := f.ReadAt(hdr, pos); err != nil ||
+			!bytes.HasPrefix(hdr, streamMagic) || hdr[3] < '1' || hdr[3] > '9' {
+			return nil, ErrNotSplittable
+		}
+
+		// The first magic must follow the header immediately
+		want := (pos + 4) * 8
+		for k < len(marks) && marks[k].Bit < want {
+			k++
+		}
+		if k == len(marks) || marks[k].Bit != want {
+			return nil, ErrNotSplittable
+		}
+
+		// Every block magic up to the footer starts a candidate
+		first := len(runs)
+		for ; k < len(marks) && !marks[k].End; k++ {
+			if k+1 == len(marks) {
+				return nil, ErrNotSplittable
+			}
+			runs = append(runs, blockRun{start: marks[k].Bit, end: marks[k+1].Bit})
+		}
+		if k == len(marks) {
+			return nil, ErrNotSplittable
+		}
+
+		// Footer: magic, combined CRC, padding to a byte boundary
+		footer := marks[k].Bit
+		k++
+		crcBits, err := ReadBits(f, footer+48, footer+80)
+		if err != nil {
+			return nil, ErrNotSplittable
+		}
+		streamCRC := binary.BigEndian.Uint32(crcBits)
+		if len(runs) == first {
+			if streamCRC != 0 {
+				return nil, ErrNotSplittable
+			}
+		} else {
+			runs[len(runs)-1].last = true
+			runs[len(runs)-1].streamCRC = streamCRC
+		}
+		pos = (footer + 80 + 7) / 8
+	}
+	if pos != size {
+		return nil, ErrNotSplittable
+	}
+
+	for i := range runs {
+		b, err := ReadBits(f, runs[i].start+48, runs[i].start+80)
+		if err != nil {
+			return nil, err
+		}
+		runs[i].crc = binary.BigEndian.Uint32(b)
+	}
+	return runs, nil
+}
+
+// bitWriter appends bits MSB first
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (w *bitWriter) writeBits(v uint64, n uint) {
+	for n > 0 {
+		c := n
+		if c > 32 {
+			c = 32
+		}
+		n -= c
+		w.acc = w.acc<<c | (v>>n)&(1<<c-1)
+		w.nbits += c
+		for w.nbits >= 8 {
+			w.nbits -= 8
+			w.buf = append(w.buf, byte(w.acc>>w.nbits))
+		}
+	}
+}
+
+// writeStream appends nbits bits taken from the start of p
+func (w *bitWriter) writeStream(p []byte, nbits int64) {
+	full := nbits / 8
+	if w.nbits == 0 {
+		w.buf = append(w.buf, p[:full]...)
+	} else {
+		for _, c := range p[:full] {
+			w.writeBits(uint64(c), 8)
+		}
+	}
+	if rem := uint(nbits % 8); rem != 0 {
+		w.writeBits(uint64(p[full]>>(8-rem)), rem)
+	}
+}
+
+// bytes pads the last byte with zeros and returns the output
+func (w *bitWriter) bytes() []byte {
+	if w.nbits > 0 {
+		w.writeBits(0, 8-w.nbits)
+	}
+	return w.buf
+}
+
+// BlockStream turns the bits [start, end) of f, which must hold whole
+// blocks, into a standalone stream. Its combined CRC is crc, which is
+// right for a single block and makes the decoder check exactly that
+// block's CRC, stored in the 32 bits after the magic
+func BlockStream(f io.ReaderAt, start, end int64, crc uint32) ([]byte, error) {
+	bits, err := ReadBits(f, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return joinBlocks([][]byte{bits}, []int64{end - start}, crc), nil
+}
+
+// joinBlocks makes a standalone stream out of the blocks made of the
+// nbits[i] bits at the start of each parts[i], in turn, with crc as
+// its combined CRC
+func joinBlocks(parts [][]byte, nbits []int64, crc uint32) []byte {
+	size := 0
+	for _, p := range parts {
+		size += len(p)
+	}
+	w := &bitWriter{buf: make([]byte, 0, size+16)}
+	w.buf = append(w.buf, 'B', 'Z', 'h', '9') // the largest block size accepts every block
+	for i, p := range parts {
+		w.writeStream(p, nbits[i])
+	}
+	w.writeBits(endMagic48, 48)
+	w.writeBits(uint64(crc), 32)
+	return w.bytes()
+}
+
+// decodeRange decodes the candidates runs[i:j] as a single block,
+// using z as the decoder
+func decodeRange(z *bzip2.Reader, f io.ReaderAt, runs []blockRun, i, j int, out *bytes.Buffer) (err error) {
+	defer recoverPanic(&err)
+	mini, err := BlockStream(f, runs[i].start, runs[j-1].end, runs[i].crc)
+	if err != nil {
+		return err
+	}
+	if err := z.Reset(bytes.NewReader(mini)); err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, z); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+// blockJob is a candidate being decoded by decodeParallel
+type blockJob struct {
+	i    int
+	out  bytes.Buffer
+	err  error
+	took time.Duration // time spent decoding
+	done chan struct{}
+}
+
+// DecompressAt decompresses the size-byte file f into w, decoding its
+// blocks concurrently and writing them in order. It returns
+// ErrNotSplittable, before writing anything, if the file's structure
+// doesn't allow it
+func DecompressAt(w io.Writer, f io.ReaderAt, size int64, opts *Options) (int64, error) {
+	return decompressAt(context.Background(), w, f, size, opts)
+}
+
+func decompressAt(ctx context.Context, w io.Writer, f io.ReaderAt, size int64, opts *Options) (int64, error) {
+	l, err := ScanLayout(f, size)
+	if err != nil {
+		return 0, err
+	}
+	return l.decode(ctx, w, f, opts)
+}
+
+// Decode decompresses f, whose layout l is, into w, see DecompressAt
+func (l *Layout) Decode(w io.Writer, f io.ReaderAt, opts *Options) (int64, error) {
+	return l.decode(context.Background(), w, f, opts)
+}
+
+// decode is Decode stopping, before the next block is written, once
+// ctx is done
+func (l *Layout) decode(ctx context.Context, w io.Writer, f io.ReaderAt, opts *Options) (int64, error) {
+	var err error
+	runs, mem := l.runs, opts.memory()
+	workers := mem.maxBlocks(opts.workers())
+	if workers < 1 {
+		workers = 1
+	}
+	slots := mem.maxBlocks(2 * workers)
+
+	work := make(chan *blockJob, slots)
+	order := make(chan *blockJob, slots)
+	stop := make(chan struct{})
+	window := make(chan struct{}, slots) // bounds jobs not yet written
+	opts.tracef(TraceDebug, "    decode: %d block candidates, %d workers, %d slots\n",
+		len(runs), workers, slots)
+
+	// Feed candidates to the workers
+	go func() {
+		defer close(work)
+		defer close(order)
+		for i := range runs {
+			select {
+			case window <- struct{}{}:
+			case <-stop:
+				return
+			}
+			if !mem.acquire(stop) {
+				<-window
+				return
+			}
+			j := &blockJob{i: i, done: make(chan struct{})}
+			order <- j
+			work <- j
+		}
+	}()
+
+	// Decode stage
+	for n := 0; n < workers; n++ {
+		go func() {
+			z, _ := getReader(nil)
+			defer putReader(z)
+			for j := range work {
+				select {
+				case <-stop:
+					close(j.done)
+					continue
+				default:
+				}
+				start := time.Now()
+				j.err = decodeRange(z, f, runs, j.i, j.i+1, &j.out)
+				j.took = time.Since(start)
+				close(j.done)
+			}
+		}()
+	}
+
+	// Write stage: check CRCs across blocks and emit in order
+	var written int64
+	var combined uint32
+	blocks, progress := 0, opts.progress()
+	skip := 0 // candidates already covered by a merged block
+	var z *bzip2.Reader
+	for j := range order {
+		<-j.done
+		if err == nil {
+			if err = ctx.Err(); err != nil {
+				close(stop)
+			}
+		}
+		if err == nil && skip > 0 {
+			skip--
+		} else if err == nil {
+			out, last := &j.out, j.i
+			if j.err != nil {
+				// A magic found by chance may have split the block:
+				// try again together with the following candidates
+				err = j.err
+				if z == nil {
+					z, _ = getReader(nil)
+					defer putReader(z)
+				}
+				for k := j.i + 1; k < len(runs) && k <= j.i+maxMerge && !runs[k-1].last; k++ {
+					var merged bytes.Buffer
+					if decodeRange(z, f, runs, j.i, k+1, &merged) == nil {
+						opts.tracef(TraceDebug, "    decode: candidates %d-%d merged into one block\n",
+							j.i+1, k+1)
+						out, last, skip, err = &merged, k, k-j.i, nil
+						break
+					}
+				}
+				if err != nil {
+					err = blockError(err, blocks+1, runs[j.i].crc, j.out.Bytes())
+				}
+			}
+			if err == nil {
+				combined = CombineCRC(combined, runs[j.i].crc)
+				if runs[last].last {
+					if combined != runs[last].streamCRC {
+						err = &ErrCRCMismatch{Block: blocks + 1, Stream: true,
+							Want: runs[last].streamCRC, Got: combined}
+					}
+					combined = 0
+				}
+			}
+			if err == nil {
+				var n int
+				n, err = w.Write(out.Bytes())
+				written += int64(n)
+				opts.tracef(TraceBlocks, "    block %d: crc = 0x%08x, %d out, %v\n",
+					j.i+1, runs[j.i].crc, n, j.took)
+				blocks++
+				if progress != nil && err == nil {
+					in := runs[last].end / 8
+					if runs[last].last {
+						in = (runs[last].end + 80 + 7) / 8
+					}
+					progress(Progress{In: in, Out: written, Blocks: blocks})
+				}
+			}
+			if err != nil {
+				close(stop)
+			}
+		}
+		mem.release()
+		<-window
+	}
+	return written, err
+}
@@ -0,0 +1,374 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+// Package bz is the engine of the bzip2 command, for Go programs that
+// want its behavior without running it. Compression splits the input
+// into blocks compressed in parallel, each as a bzip2 stream of its
+// own, which any bzip2 decoder reads back as a single multi-stream
+// file. Decompression decodes the blocks of a file in parallel, those
+// of files made by stock bzip2 included, and falls back to a serial
+// decode when the file's structure doesn't allow it.
+//
+// CompressFile, DecompressFile and Test work on files as bzip2 FILE,
+// bzip2 -d FILE and bzip2 -t FILE do, writing outputs the same way;
+// Compress, DecompressAt, TestAt and NewReader work on streams of
+// data. A ParallelWriter compresses what is written to it, and a
+// ParallelReader decodes the blocks of what it reads in parallel,
+// without needing the whole file at hand.
+//
+// The functions ending in Context can be cancelled: the goroutines
+// stop once the blocks in progress are done, and output files being
+// written are removed.
+package bz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pedroalbanese/bzip2/internal/fileio"
+)
+
+// BlockSize is the bzip2 block size unit: level N uses N * BlockSize
+const BlockSize = 100000
+
+// Trace levels, as passed to Options.Trace
+const (
+	TraceBlocks = 2 // per-block stats and timings
+	TraceDebug  = 3 // pipeline and worker diagnostics
+)
+
+// Options control compression and decompression. The zero value, or
+// a nil *Options, is bzip2 run with no options
+type Options struct {
+	Level   int          // 1 (fastest) to 9 (best, the default)
+	Workers int          // blocks (de)compressed at once, all CPUs if 0
+	Memory  *MemoryLimit // bounds the blocks held in memory, if set
+	Verify  bool         // check each compressed block, see Config
+
+	// The following apply to CompressFile and DecompressFile
+	Keep   bool   // keep the input file, as with -k
+	Force  bool   // replace existing output files, as with -f
+	Suffix string // of compressed files, without the dot; bz2 if empty
+
+	// Trace, if set, receives diagnostics at the levels above
+	Trace func(level int, format string, a ...interface{})
+
+	// Progress, if set, is called as blocks are done; not when a
+	// file is decoded serially
+	Progress func(Progress)
+}
+
+// Progress is how far a compression or decompression got, as passed
+// to the Progress callbacks after each block, from the goroutine
+// writing the output or from Read; they shouldn't hold it up
+type Progress struct {
+	In, Out int64 // bytes read and written so far
+	Blocks  int   // blocks done
+}
+
+func (o *Options) progress() func(Progress) {
+	if o == nil {
+		return nil
+	}
+	return o.Progress
+}
+
+func (o *Options) level() int {
+	if o == nil || o.Level == 0 {
+		return 9
+	}
+	return o.Level
+}
+
+func (o *Options) workers() int {
+	if o == nil || o.Workers < 1 {
+		return runtime.NumCPU()
+	}
+	return o.Workers
+}
+
+func (o *Options) memory() *MemoryLimit {
+	if o == nil {
+		return nil
+	}
+	return o.Memory
+}
+
+func (o *Options) tracef(level int, format string, a ...interface{}) {
+	if o != nil && o.Trace != nil {
+		o.Trace(level, format, a...)
+	}
+}
+
+// suffixes returns the suffixes recognized on compressed files: the
+// one set, or nil for the bzip2(1) table
+func (o *Options) suffixes() []string {
+	if o == nil || o.Suffix == "" {
+		return nil
+	}
+	return []string{o.Suffix}
+}
+
+func (o *Options) suffix() string {
+	if o == nil || o.Suffix == "" {
+		return "bz2"
+	}
+	return o.Suffix
+}
+
+// suffixMap is the bzip2(1) table of compressed file suffixes and
+// what replaces them on decompression, longest first
+var suffixMap = []struct{ from, to string }{
+	{".tbz2", ".tar"},
+	{".tbz", ".tar"},
+	{".bz2", ""},
+	{".bz", ""},
+}
+
+// MatchSuffix returns the compressed suffix name ends with and its
+// replacement on decompression, or empty strings if there's none.
+// With suffixes, given without their leading dot, those are the only
+// ones recognized and the longest matching one is used; otherwise it
+// is the bzip2(1) table of .bz2, .bz, .tbz2 and .tbz
+func MatchSuffix(name string, suffixes []string) (from, to string) {
+	if suffixes != nil {
+		for _, s := range suffixes {
+			fext := "." + s
+			if strings.HasSuffix(name, fext) && len(fext) > len(from) {
+				from = fext
+			}
+		}
+		// Known suffixes still get their replacement
+		for _, m := range suffixMap {
+			if m.from == from {
+				return from, m.to
+			}
+		}
+		return from, ""
+	}
+	for _, s := range suffixMap {
+		if strings.HasSuffix(name, s.from) {
+			return s.from, s.to
+		}
+	}
+	return "", ""
+}
+
+// CompressFile compresses the regular file name into name.bz2, or
+// the suffix of opts, and removes it unless opts.Keep is set. The
+// output is written under a temporary name and renamed into place
+// once complete, with the owner, mode, attributes and times of name,
+// as the bzip2 command does. It returns the name of the output
+func CompressFile(name string, opts *Options) (string, error) {
+	return CompressFileContext(context.Background(), name, opts)
+}
+
+// CompressFileContext is CompressFile with a context cancelling the
+// compression, in which case the partial output is removed
+func CompressFileContext(ctx context.Context, name string, opts *Options) (string, error) {
+	fi, err := regularFile(name)
+	if err != nil {
+		return "", err
+	}
+	fext := "." + opts.suffix()
+	if strings.HasSuffix(name, fext) {
+		return "", fmt.Errorf("input file %s already has %s suffix", name, fext)
+	}
+	out := name + fext
+	err = writeFile(out, fi, opts, func(w io.Writer, in *os.File) (int64, error) {
+		_, n, err := compress(ctx, w, in, opts)
+		return n, err
+	}, name)
+	if err != nil {
+		return "", err
+	}
+	return out, removeInput(name, out, opts)
+}
+
+// DecompressFile decompresses the regular file name into a file named
+// without its compressed suffix, or name.out if it has none, and
+// removes it unless opts.Keep is set. The output is written as with
+// CompressFile. It returns the name of the output
+func DecompressFile(name string, opts *Options) (string, error) {
+	return DecompressFileContext(context.Background(), name, opts)
+}
+
+// DecompressFileContext is DecompressFile with a context cancelling
+// the decompression, in which case the partial output is removed
+func DecompressFileContext(ctx context.Context, name string, opts *Options) (string, error) {
+	fi, err := regularFile(name)
+	if err != nil {
+		return "", err
+	}
+	out := name + ".out"
+	if from, to := MatchSuffix(name, opts.suffixes()); from != "" {
+		out = strings.TrimSuffix(name, from) + to
+	}
+	err = writeFile(out, fi, opts, func(w io.Writer, in *os.File) (int64, error) {
+		n, err := decompressAt(ctx, w, in, fi.Size(), opts)
+		if err != ErrNotSplittable {
+			return n, err
+		}
+		opts.tracef(TraceDebug, "    %s: %v, decoding serially\n", name, err)
+		return decompress(w, &contextReader{ctx: ctx, r: in})
+	}, name)
+	if err != nil {
+		return "", err
+	}
+	return out, removeInput(name, out, opts)
+}
+
+// Test checks the integrity of the compressed file name by decoding
+// it. Blocks are decoded in parallel where the file's structure allows
+// it, as with TestAt; other files are decoded serially
+func Test(name string, opts *Options) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		_, err = decompress(io.Discard, f)
+		return err
+	}
+	return TestAt(f, fi.Size(), opts)
+}
+
+// TestAt checks the integrity of the size bytes of compressed data in
+// r, decoding its blocks in parallel where its structure allows it.
+// Any failure is confirmed with a serial decode, which yields the
+// error a serial decoder would have reported
+func TestAt(r io.ReaderAt, size int64, opts *Options) error {
+	_, perr := DecompressAt(io.Discard, r, size, opts)
+	if perr == nil {
+		return nil
+	}
+	opts.tracef(TraceDebug, "    test: %v, checking serially\n", perr)
+	_, err := decompress(io.Discard, io.NewSectionReader(r, 0, size))
+	// The parallel decode knows which block failed, the serial one
+	// doesn't
+	var serial, parallel *ErrCRCMismatch
+	if errors.As(err, &serial) && serial.Block == 0 && errors.As(perr, &parallel) &&
+		serial.Stream == parallel.Stream {
+		return perr
+	}
+	return err
+}
+
+// decompress decodes r into w serially
+func decompress(w io.Writer, r io.Reader) (int64, error) {
+	z, err := NewReader(r)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(w, z)
+	if cerr := z.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+// contextReader reads r until ctx is done, then fails with its error
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// regularFile returns the information of name, which must be a
+// regular file
+func regularFile(name string) (os.FileInfo, error) {
+	fi, err := os.Lstat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", name)
+	}
+	return fi, nil
+}
+
+// writeFile writes the output name with fill, given the opened input
+// in whose information is fi, the way the bzip2 command writes its
+// outputs (see package fileio): under a temporary name in the same
+// directory, synced if the input is to be removed, and given the
+// input's metadata before it's renamed into place
+func writeFile(name string, fi os.FileInfo, opts *Options,
+	fill func(w io.Writer, in *os.File) (int64, error), input string) (err error) {
+	if fileio.SameFile(fi, name) {
+		return fmt.Errorf("input and output %s are the same file", name)
+	}
+	if _, err := os.Lstat(name); err == nil && (opts == nil || !opts.Force) {
+		return fmt.Errorf("output file %s exists", name)
+	}
+	in, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := fileio.TempName(name)
+	f, err := fileio.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(tmp)
+		}
+	}()
+	n, err := fill(f, in)
+	if err != nil {
+		return err
+	}
+	if opts == nil || !opts.Keep {
+		if err := f.Sync(); err != nil {
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := fileio.CheckSize(tmp, n); err != nil {
+		return err
+	}
+	trace := func(format string, a ...interface{}) { opts.tracef(TraceDebug, format, a...) }
+	if err := fileio.PreserveMeta(input, fi, tmp, &fileio.MetaOptions{Warn: trace, Info: trace}); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// removeInput removes the input name once its output out is in place
+// and on disk, unless opts.Keep is set
+func removeInput(name, out string, opts *Options) error {
+	if opts != nil && opts.Keep {
+		return nil
+	}
+	if err := fileio.SyncDir(filepath.Dir(out)); err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
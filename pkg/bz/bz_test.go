@@ -0,0 +1,164 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testData returns n bytes mixing text-like runs with noise,
+// deterministically
+func testData(n int) []byte {
+	r := rand.New(rand.NewSource(1))
+	data := make([]byte, 0, n)
+	words := []string{"bzip2 ", "block ", "stream ", "\n", "aaaaaaaaaaaaaaaa", "0123456789"}
+	for len(data) < n {
+		if r.Intn(8) == 0 {
+			data = append(data, byte(r.Intn(256)))
+		} else {
+			data = append(data, words[r.Intn(len(words))]...)
+		}
+	}
+	return data[:n]
+}
+
+// compressTest compresses data with a ParallelWriter set by cfg
+func compressTest(t *testing.T, data []byte, cfg *Config) []byte {
+	t.Helper()
+	var z bytes.Buffer
+	p, err := NewParallelWriter(&z, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return z.Bytes()
+}
+
+// decompressSerial decompresses z with a Reader
+func decompressSerial(t *testing.T, z []byte) []byte {
+	t.Helper()
+	r, err := NewReader(bytes.NewReader(z))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(r)
+	if cerr := r.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestMatchSuffix(t *testing.T) {
+	tests := []struct {
+		name     string
+		suffixes []string
+		from, to string
+	}{
+		{"a.bz2", nil, ".bz2", ""},
+		{"a.tbz2", nil, ".tbz2", ".tar"},
+		{"a.tbz", nil, ".tbz", ".tar"},
+		{"a.txt", nil, "", ""},
+		{"a.gz", []string{"gz"}, ".gz", ""},
+		{"a.bz2", []string{"gz"}, "", ""},
+	}
+	for _, tt := range tests {
+		from, to := MatchSuffix(tt.name, tt.suffixes)
+		if from != tt.from || to != tt.to {
+			t.Errorf("MatchSuffix(%q, %q) = %q, %q, want %q, %q",
+				tt.name, tt.suffixes, from, to, tt.from, tt.to)
+		}
+	}
+}
+
+// The file functions write outputs as the command does: with the mode
+// and times of the input, which is removed unless kept
+func TestFileRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "a")
+	data := testData(300000)
+	if err := os.WriteFile(name, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(name, 0640); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(name, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	opts := &Options{Level: 1, Workers: 2}
+	z, err := CompressFile(name, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(name); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("input left behind: %v", err)
+	}
+	if err := Test(z, opts); err != nil {
+		t.Fatal(err)
+	}
+	out, err := DecompressFile(z, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("DecompressFile wrote %d bytes, %v, want %d", len(got), err, len(data))
+	}
+	fi, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0640 || !fi.ModTime().Equal(mtime) {
+		t.Errorf("output has mode %#o and time %v, want %#o and %v", fi.Mode().Perm(), fi.ModTime(), 0640, mtime)
+	}
+}
+
+// An output that is the input under another name is refused, even with
+// Force
+func TestFileSameFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "a")
+	if err := os.WriteFile(name, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(name, name+".bz2"); err != nil {
+		t.Skip(err)
+	}
+	if _, err := CompressFile(name, &Options{Force: true}); err == nil {
+		t.Fatal("compressed a file onto itself")
+	}
+	if got, err := os.ReadFile(name); err != nil || string(got) != "hello" {
+		t.Errorf("input is now %q, %v", got, err)
+	}
+}
+
+func TestTestAt(t *testing.T) {
+	z := compressTest(t, testData(250000), &Config{Level: 1, Workers: 2})
+	if err := TestAt(bytes.NewReader(z), int64(len(z)), nil); err != nil {
+		t.Fatal(err)
+	}
+	bad := append([]byte(nil), z...)
+	bad[len(bad)/2] ^= 0x55
+	if err := TestAt(bytes.NewReader(bad), int64(len(bad)), nil); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("TestAt of damaged data = %v, want a match for ErrCorrupt", err)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriterContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var z bytes.Buffer
+	p, err := NewWriterContext(ctx, &z, &Config{Level: 1, Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Write(testData(250000)); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	_, err = p.Write(testData(250000))
+	if cerr := p.Close(); err == nil {
+		err = cerr
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled ParallelWriter: %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestReaderContext(t *testing.T) {
+	z := compressTest(t, testData(500000), &Config{Level: 1, Workers: 2})
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewReaderContext(ctx, bytes.NewReader(z), &ReaderConfig{Workers: 2})
+	defer p.Close()
+	if _, err := p.Read(make([]byte, 10)); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := io.Copy(io.Discard, p); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled ParallelReader: %v, want %v", err, context.Canceled)
+	}
+}
+
+// A cancelled file compression leaves the input and nothing else
+func TestCompressFileContext(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "a")
+	if err := os.WriteFile(name, testData(300000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := CompressFileContext(ctx, name, &Options{Level: 1}); !errors.Is(err, context.Canceled) {
+		t.Errorf("CompressFileContext cancelled: %v, want %v", err, context.Canceled)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "a" {
+		t.Errorf("cancelled CompressFileContext left %v", entries)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import "math/bits"
+
+// bzip2 uses CRC-32 with the IEEE polynomial, processing bits MSB
+// first. The CRCs that -d and -t depend on are computed and checked
+// inside the decoder, which already goes through hash/crc32 and can't
+// be handed another implementation, so nothing here is on their path.
+// UpdateCRC is for the CRCs computed around the decoder, such as that
+// of a block found to be damaged, and for the self test, none of which
+// need to be fast
+
+// crcTable holds the CRC of each byte value, MSB first
+var crcTable = func() (t [256]uint32) {
+	for i := range t {
+		c := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if c&0x80000000 != 0 {
+				c = c<<1 ^ 0x04c11db7
+			} else {
+				c <<= 1
+			}
+		}
+		t[i] = c
+	}
+	return t
+}()
+
+// UpdateCRC returns the bzip2 CRC of the data hashed into crc followed
+// by p. The CRC of a block starts from 0
+func UpdateCRC(crc uint32, p []byte) uint32 {
+	crc = ^crc
+	for _, b := range p {
+		crc = crc<<8 ^ crcTable[byte(crc>>24)^b]
+	}
+	return ^crc
+}
+
+// CombineCRC folds the CRC of the next block into the combined CRC of
+// a stream, as stored in its footer
+func CombineCRC(combined, block uint32) uint32 {
+	return bits.RotateLeft32(combined, 1) ^ block
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"bytes"
+	"testing"
+)
+
+// slowCRC is the bzip2 CRC computed a bit at a time, MSB first
+func slowCRC(crc uint32, p []byte) uint32 {
+	crc = ^crc
+	for _, b := range p {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return ^crc
+}
+
+func TestUpdateCRC(t *testing.T) {
+	tests := []struct {
+		in   string
+		want uint32
+	}{
+		{"", 0},
+		{"123456789", 0xfc891918}, // the check value of CRC-32/BZIP2
+		{"hello, world\n", slowCRC(0, []byte("hello, world\n"))},
+	}
+	for _, tt := range tests {
+		if got := UpdateCRC(0, []byte(tt.in)); got != tt.want {
+			t.Errorf("UpdateCRC(0, %q) = 0x%08x, want 0x%08x", tt.in, got, tt.want)
+		}
+	}
+}
+
+// Long inputs may be hashed in parts
+func TestUpdateCRCLong(t *testing.T) {
+	data := testData(10000)
+	want := slowCRC(0, data)
+	if got := UpdateCRC(0, data); got != want {
+		t.Fatalf("UpdateCRC of %d bytes = 0x%08x, want 0x%08x", len(data), got, want)
+	}
+	for _, cut := range []int{1, 7, 8, 9, 4096, len(data) - 1} {
+		if got := UpdateCRC(UpdateCRC(0, data[:cut]), data[cut:]); got != want {
+			t.Errorf("UpdateCRC cut at %d = 0x%08x, want 0x%08x", cut, got, want)
+		}
+	}
+}
+
+// The block CRC stored in a stream is that of its data, and the
+// combined CRC in the footer that of its only block
+func TestCRCOfStream(t *testing.T) {
+	data := []byte("hello, world\n")
+	var z bytes.Buffer
+	if _, _, err := Compress(&z, bytes.NewReader(data), nil); err != nil {
+		t.Fatal(err)
+	}
+	it := Streams(bytes.NewReader(z.Bytes()))
+	if !it.Next() {
+		t.Fatal(it.Err())
+	}
+	crc := UpdateCRC(0, data)
+	if got := it.Stream().CRC; got != CombineCRC(0, crc) {
+		t.Errorf("stream CRC = 0x%08x, want 0x%08x", got, CombineCRC(0, crc))
+	}
+}
+
+func TestCombineCRC(t *testing.T) {
+	if got := CombineCRC(0x80000001, 0x10); got != 0x00000013 {
+		t.Errorf("CombineCRC(0x80000001, 0x10) = 0x%08x, want 0x00000013", got)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Decompression errors come in the classes below, so callers can
+// branch on them with errors.Is and errors.As rather than on their
+// text. Every corruption matches ErrCorrupt, whether this package or
+// the decoder found it; the errors of the decoder are told apart by
+// their messages, which is all it exports of them
+
+// Errors of corrupted input
+var (
+	ErrCorrupt         = corruptError("bzip2: corrupted input")
+	ErrNotBzip2        = corruptError("bzip2: corrupted input: not bzip2 data")
+	ErrTrailingGarbage = corruptError("bzip2: corrupted input: trailing garbage after the last stream")
+)
+
+// Messages of the decoder's errors that have a class of their own
+const (
+	decoderStreamMagic = "bzip2: corrupted input: invalid stream magic"
+	decoderBlockCRC    = "bzip2: corrupted input: mismatching block checksum"
+	decoderStreamCRC   = "bzip2: corrupted input: mismatching stream checksum"
+)
+
+// corruptError is a corruption detected here rather than by the
+// decoder
+type corruptError string
+
+func (e corruptError) Error() string { return string(e) }
+
+// IsCorrupted matches the method of the decoder's errors
+func (e corruptError) IsCorrupted() bool { return true }
+
+func (e corruptError) Is(target error) bool { return target == ErrCorrupt }
+
+// ErrCRCMismatch is data that doesn't match its stored CRC, that of a
+// block or, with Stream set, the combined CRC of the stream ending
+// with it. Block counts from 1 in the data decoded; it and the CRCs
+// are zero when the serial decoder found the mismatch, as it doesn't
+// tell them
+type ErrCRCMismatch struct {
+	Block     int
+	Stream    bool
+	Want, Got uint32
+}
+
+func (e *ErrCRCMismatch) Error() string {
+	what := "block"
+	if e.Stream {
+		what = "stream"
+	}
+	if e.Block == 0 {
+		return "bzip2: corrupted input: mismatching " + what + " checksum"
+	}
+	return fmt.Sprintf("bzip2: corrupted input: mismatching %s checksum at block %d: 0x%08x stored, 0x%08x computed",
+		what, e.Block, e.Want, e.Got)
+}
+
+// IsCorrupted matches the method of the decoder's errors
+func (e *ErrCRCMismatch) IsCorrupted() bool { return true }
+
+func (e *ErrCRCMismatch) Is(target error) bool { return target == ErrCorrupt }
+
+// decoderError is a corruption found by the decoder, without a class
+// of its own
+type decoderError struct {
+	err error
+}
+
+func (e decoderError) Error() string        { return e.err.Error() }
+func (e decoderError) Unwrap() error        { return e.err }
+func (e decoderError) IsCorrupted() bool    { return true }
+func (e decoderError) Is(target error) bool { return target == ErrCorrupt }
+
+// classify turns an error of the decoder into one of the classes
+// above; data tells whether any data was decoded before it
+func classify(err error, data bool) error {
+	var c interface{ IsCorrupted() bool }
+	if err == nil || errors.Is(err, ErrCorrupt) || !errors.As(err, &c) || !c.IsCorrupted() {
+		return err
+	}
+	switch err.Error() {
+	case decoderStreamMagic:
+		if data {
+			return ErrTrailingGarbage
+		}
+		return ErrNotBzip2
+	case decoderBlockCRC:
+		return &ErrCRCMismatch{}
+	case decoderStreamCRC:
+		return &ErrCRCMismatch{Stream: true}
+	}
+	return decoderError{err}
+}
+
+// panicError is a panic recovered in a goroutine of this package,
+// returned as the error of the block it happened in rather than
+// ending the program
+type panicError struct {
+	v interface{}
+}
+
+func (e panicError) Error() string { return fmt.Sprintf("bz: internal error: %v", e.v) }
+
+// IsInternal tells callers the error is a bug, not bad data
+func (e panicError) IsInternal() bool { return true }
+
+// recoverPanic turns a panic into a panicError in *err; it has to be
+// deferred
+func recoverPanic(err *error) {
+	if v := recover(); v != nil {
+		*err = panicError{v}
+	}
+}
+
+// blockError classifies err, the failure to decode block number
+// block, whose stored CRC is crc, into out
+func blockError(err error, block int, crc uint32, out []byte) error {
+	if err != nil && err.Error() == decoderBlockCRC {
+		return &ErrCRCMismatch{Block: block, Want: crc, Got: UpdateCRC(0, out)}
+	}
+	return classify(err, true)
+}
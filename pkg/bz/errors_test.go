@@ -0,0 +1,96 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The readers tell corrupted data apart, and agree on it
+func TestReaderErrors(t *testing.T) {
+	data := testData(200000)
+	z := compressTest(t, data, &Config{Level: 1, Workers: 2})
+	flipped := append([]byte(nil), z...)
+	flipped[len(z)/3] ^= 0x01
+
+	tests := []struct {
+		name string
+		z    []byte
+		want error
+	}{
+		{"not bzip2", []byte("hello, world\n"), ErrNotBzip2},
+		{"trailing garbage", append(append([]byte(nil), z...), "garbage"...), ErrTrailingGarbage},
+		{"flipped bit", flipped, ErrCorrupt},
+		{"truncated", z[:len(z)-10], io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		p := NewParallelReader(bytes.NewReader(tt.z), &ReaderConfig{Workers: 2})
+		_, err := io.Copy(io.Discard, p)
+		p.Close()
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: ParallelReader: %v, want %v", tt.name, err, tt.want)
+		}
+
+		r, err := NewReader(bytes.NewReader(tt.z))
+		if err == nil {
+			_, err = io.Copy(io.Discard, r)
+			r.Close()
+		}
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: Reader: %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// A block CRC that doesn't match its data is an ErrCRCMismatch naming
+// the block, with both CRCs
+func TestCRCMismatch(t *testing.T) {
+	parts := [][]byte{testData(1000), testData(2000), testData(3000)}
+	var z []byte
+	var offs []int
+	for _, part := range parts {
+		offs = append(offs, len(z))
+		z = append(z, compressTest(t, part, &Config{Level: 1})...)
+	}
+	// The block CRC follows the 4-byte header and 6-byte block magic
+	crcAt := offs[1] + 10
+	z[crcAt] ^= 0x80
+	want := &ErrCRCMismatch{
+		Block: 2,
+		Want:  UpdateCRC(0, parts[1]) ^ 0x80000000,
+		Got:   UpdateCRC(0, parts[1]),
+	}
+
+	p := NewParallelReader(bytes.NewReader(z), nil)
+	_, err := io.Copy(io.Discard, p)
+	p.Close()
+	var got *ErrCRCMismatch
+	if !errors.As(err, &got) || *got != *want || !errors.Is(err, ErrCorrupt) {
+		t.Errorf("ParallelReader: %v, want %v", err, want)
+	}
+
+	// The serial decoder doesn't tell which block
+	r, _ := NewReader(bytes.NewReader(z))
+	_, err = io.Copy(io.Discard, r)
+	r.Close()
+	if !errors.As(err, &got) || got.Block != 0 || got.Stream || !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Reader: %v, want a block CRC mismatch", err)
+	}
+
+	// Test gets the block from the parallel decode
+	name := filepath.Join(t.TempDir(), "a.bz2")
+	if err := os.WriteFile(name, z, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Test(name, nil); !errors.As(err, &got) || *got != *want {
+		t.Errorf("Test: %v, want %v", err, want)
+	}
+}
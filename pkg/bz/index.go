@@ -0,0 +1,167 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// An Index maps the blocks of a compressed file to the offsets of
+// their data, so a part of the file can be decoded without the blocks
+// before it. Building one takes a full decode; it can then be kept in
+// a sidecar file next to the compressed one. The encoding starts with
+// indexMagic and a version byte, followed by varints: the block count,
+// the sizes, then for each block the gap since the end of the previous
+// one, its length in bits and the size of its data, and its CRC
+
+// IndexSuffix is appended to a compressed file's name for its index
+const IndexSuffix = ".idx"
+
+// indexMagic and indexVersion start an encoded Index
+const (
+	indexMagic   = "BZIX"
+	indexVersion = 1
+)
+
+// maxIndexValue bounds the offsets and sizes in an index read, which
+// keeps the sums of a damaged one from overflowing
+const maxIndexValue = 1 << 56
+
+// errBadIndex is returned by ReadIndex for data that isn't an index
+var errBadIndex = errors.New("bz: invalid index")
+
+// IndexBlock is the position of a block, in bits of the compressed
+// data, and of its first byte in the decompressed data
+type IndexBlock struct {
+	Start, End int64  // from the block magic to the next magic
+	Offset     int64  // of the data
+	CRC        uint32 // block CRC as stored after the magic
+}
+
+// Index is the block table of a compressed file, in order
+type Index struct {
+	Blocks         []IndexBlock
+	Size           int64 // of the decompressed data
+	CompressedSize int64 // bytes of the streams indexed
+}
+
+// BuildIndex decodes the compressed data of r, in parallel as a
+// ParallelReader does, and returns the index of its blocks
+func BuildIndex(r io.Reader) (*Index, error) {
+	p := NewParallelReader(r, nil)
+	idx := &Index{}
+	p.index = idx
+	n, err := p.WriteTo(io.Discard)
+	if cerr := p.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	idx.Size = n
+	return idx, nil
+}
+
+// Find returns the block holding the data at offset off, or -1 if off
+// is past the end of the data
+func (x *Index) Find(off int64) int {
+	if off < 0 || off >= x.Size {
+		return -1
+	}
+	return sort.Search(len(x.Blocks), func(i int) bool {
+		return x.blockEnd(i) > off
+	})
+}
+
+// blockEnd returns the offset of the data that follows block i
+func (x *Index) blockEnd(i int) int64 {
+	if i+1 < len(x.Blocks) {
+		return x.Blocks[i+1].Offset
+	}
+	return x.Size
+}
+
+// WriteTo writes the encoding of the index to w
+func (x *Index) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	b.WriteString(indexMagic)
+	b.WriteByte(indexVersion)
+	var v [binary.MaxVarintLen64]byte
+	put := func(n int64) {
+		b.Write(v[:binary.PutUvarint(v[:], uint64(n))])
+	}
+	put(int64(len(x.Blocks)))
+	put(x.Size)
+	put(x.CompressedSize)
+	var end int64
+	for i, blk := range x.Blocks {
+		put(blk.Start - end)
+		put(blk.End - blk.Start)
+		put(x.blockEnd(i) - blk.Offset)
+		binary.BigEndian.PutUint32(v[:4], blk.CRC)
+		b.Write(v[:4])
+		end = blk.End
+	}
+	return b.WriteTo(w)
+}
+
+// ReadIndex reads an index written by Index.WriteTo
+func ReadIndex(r io.Reader) (*Index, error) {
+	br := bufio.NewReader(r)
+	hdr := make([]byte, len(indexMagic)+1)
+	if _, err := io.ReadFull(br, hdr); err != nil {
+		return nil, errBadIndex
+	}
+	if string(hdr[:len(indexMagic)]) != indexMagic {
+		return nil, errBadIndex
+	}
+	if hdr[len(indexMagic)] != indexVersion {
+		return nil, fmt.Errorf("bz: unsupported index version %d", hdr[len(indexMagic)])
+	}
+	var err error
+	get := func() int64 {
+		if err != nil {
+			return 0
+		}
+		var n uint64
+		n, err = binary.ReadUvarint(br)
+		if err == nil && n > maxIndexValue {
+			err = errBadIndex
+		}
+		return int64(n)
+	}
+	count, size, csize := get(), get(), get()
+	if err != nil || count > csize {
+		return nil, errBadIndex
+	}
+	x := &Index{Size: size, CompressedSize: csize}
+	var end, off int64
+	crc := make([]byte, 4)
+	for i := int64(0); i < count; i++ {
+		blk := IndexBlock{Start: end + get(), Offset: off}
+		blk.End = blk.Start + get()
+		off += get()
+		if err == nil {
+			_, err = io.ReadFull(br, crc)
+		}
+		if err != nil || blk.End > csize*8 || off > size {
+			return nil, errBadIndex
+		}
+		blk.CRC = binary.BigEndian.Uint32(crc)
+		x.Blocks = append(x.Blocks, blk)
+		end = blk.End
+	}
+	if off != size {
+		return nil, errBadIndex
+	}
+	return x, nil
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestIndexRoundTrip(t *testing.T) {
+	tests := []*Index{
+		{},
+		{Size: 5, CompressedSize: 50, Blocks: []IndexBlock{
+			{Start: 80, End: 400, Offset: 0, CRC: 0xfc891918},
+		}},
+		{Size: 300, CompressedSize: 200, Blocks: []IndexBlock{
+			{Start: 32, End: 500, Offset: 0, CRC: 1},
+			{Start: 500, End: 900, Offset: 100, CRC: 0xffffffff},
+			{Start: 1000, End: 1600, Offset: 250},
+			{Start: 1600, End: 1600, Offset: 300}, // an empty block
+		}},
+	}
+	for _, x := range tests {
+		var b bytes.Buffer
+		n, err := x.WriteTo(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(b.Len()) {
+			t.Errorf("WriteTo returned %d, wrote %d bytes", n, b.Len())
+		}
+		y, err := ReadIndex(&b)
+		if err != nil {
+			t.Fatalf("ReadIndex of %+v: %v", x, err)
+		}
+		if !reflect.DeepEqual(x, y) {
+			t.Errorf("ReadIndex = %+v, want %+v", y, x)
+		}
+	}
+}
+
+func TestReadIndexInvalid(t *testing.T) {
+	x := &Index{Size: 300, CompressedSize: 200, Blocks: []IndexBlock{
+		{Start: 32, End: 500, Offset: 0},
+		{Start: 500, End: 900, Offset: 100},
+	}}
+	var b bytes.Buffer
+	x.WriteTo(&b)
+	good := b.Bytes()
+
+	// Any truncation leaves an invalid index
+	for i := 0; i < len(good); i++ {
+		if _, err := ReadIndex(bytes.NewReader(good[:i])); err != errBadIndex {
+			t.Errorf("ReadIndex of %d of %d bytes: %v, want %v", i, len(good), err, errBadIndex)
+		}
+	}
+
+	encode := func(x *Index) []byte {
+		var b bytes.Buffer
+		x.WriteTo(&b)
+		return b.Bytes()
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"bad magic", append([]byte("BZIY"), good[4:]...)},
+		{"more blocks than bytes", encode(&Index{Size: 0, CompressedSize: 1,
+			Blocks: make([]IndexBlock, 2)})},
+		{"block past the end", encode(&Index{Size: 1, CompressedSize: 1,
+			Blocks: []IndexBlock{{Start: 0, End: 9}}})},
+		{"data past the size", append([]byte(indexMagic+"\x01\x01\x01\x08\x00\x08\x02"), 0, 0, 0, 0)},
+		{"data short of the size", append([]byte(indexMagic+"\x01\x01\x02\x08\x00\x08\x01"), 0, 0, 0, 0)},
+		{"huge value", []byte(indexMagic + "\x01\xff\xff\xff\xff\xff\xff\xff\xff\x01")},
+	}
+	for _, tt := range tests {
+		if _, err := ReadIndex(bytes.NewReader(tt.data)); err != errBadIndex {
+			t.Errorf("ReadIndex of %s: %v, want %v", tt.name, err, errBadIndex)
+		}
+	}
+
+	data := append([]byte(indexMagic+"\x02"), good[5:]...)
+	if _, err := ReadIndex(bytes.NewReader(data)); err == nil || err == errBadIndex {
+		t.Errorf("ReadIndex of version 2: %v, want an unsupported version", err)
+	}
+}
+
+// BuildIndex finds every block of every stream, with its data
+func TestBuildIndex(t *testing.T) {
+	data := testData(350000)
+	var z []byte
+	for _, part := range [][]byte{data[:100], data[100:250000], data[250000:]} {
+		z = append(z, compressTest(t, part, &Config{Level: 1, Workers: 2})...)
+	}
+	idx, err := BuildIndex(bytes.NewReader(z))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.Size != int64(len(data)) || idx.CompressedSize != int64(len(z)) {
+		t.Fatalf("index sizes %d, %d, want %d, %d", idx.Size, idx.CompressedSize, len(data), len(z))
+	}
+	if len(idx.Blocks) < 4 {
+		t.Fatalf("index of %d blocks, want at least 4", len(idx.Blocks))
+	}
+	for i, blk := range idx.Blocks {
+		end := idx.blockEnd(i)
+		if got := UpdateCRC(0, data[blk.Offset:end]); got != blk.CRC {
+			t.Errorf("block %d: CRC 0x%08x, data has 0x%08x", i, blk.CRC, got)
+		}
+		if idx.Find(blk.Offset) != i || idx.Find(end-1) != i {
+			t.Errorf("Find doesn't return block %d for its data", i)
+		}
+	}
+	if idx.Find(-1) != -1 || idx.Find(idx.Size) != -1 {
+		t.Errorf("Find returns a block outside the data")
+	}
+
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+// MemoryLimit bounds how many blocks may be held in memory at once,
+// across all the compressions and decompressions sharing it. A nil
+// MemoryLimit means no limit
+type MemoryLimit struct {
+	tokens chan struct{}
+}
+
+// BlockMemory estimates the memory one in-flight block costs at the
+// given level: its input buffer, its compressed output, and the share
+// of the worker's encoder or decoder tables it keeps busy. The factor
+// comes from measured RSS, garbage collector headroom included
+func BlockMemory(level int) int64 {
+	return 30 * int64(level) * BlockSize
+}
+
+// NewMemoryLimit returns a limit keeping in-flight blocks at level
+// within limit bytes. At least one block is always allowed, otherwise
+// nothing could make progress
+func NewMemoryLimit(limit int64, level int) *MemoryLimit {
+	n := limit / BlockMemory(level)
+	if n < 1 {
+		n = 1
+	}
+	return &MemoryLimit{tokens: make(chan struct{}, n)}
+}
+
+// maxBlocks caps a per-file block count by the memory limit
+func (m *MemoryLimit) maxBlocks(n int) int {
+	if m != nil && cap(m.tokens) < n {
+		return cap(m.tokens)
+	}
+	return n
+}
+
+// acquire waits until another block fits in the memory limit, giving
+// up if stop is closed first
+func (m *MemoryLimit) acquire(stop <-chan struct{}) bool {
+	if m == nil {
+		return true
+	}
+	select {
+	case m.tokens <- struct{}{}:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// release returns the memory of a block acquired with acquire
+func (m *MemoryLimit) release() {
+	if m != nil {
+		<-m.tokens
+	}
+}
@@ -0,0 +1,570 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/dsnet/compress/bzip2"
+)
+
+// block is a unit of work for the parallel compressor: a chunk of
+// input and the self-contained bzip2 stream it compresses to
+type block struct {
+	in   []byte
+	out  bytes.Buffer
+	err  error
+	took time.Duration // time spent compressing
+	done chan struct{}
+}
+
+// ring is a bounded set of reusable blocks. Input has to take a free
+// slot before it is read, so it can never get more than n blocks
+// ahead of the output, which bounds memory use. Slots are made as
+// they are needed and their buffers grow with the input, so small
+// inputs stay small
+type ring struct {
+	free chan *block
+	n    int // slots at most
+	made int // slots made so far, by the goroutine calling get
+	mem  *MemoryLimit
+}
+
+// minSlot is the input buffer a slot starts with
+const minSlot = 64 << 10
+
+// newRing returns a ring of up to n slots
+func newRing(n int, mem *MemoryLimit) *ring {
+	return &ring{free: make(chan *block, n), n: n, mem: mem}
+}
+
+// get waits for a free slot, and for room under the memory limit,
+// giving up if stop is closed first
+func (r *ring) get(stop <-chan struct{}) (*block, bool) {
+	var b *block
+	select {
+	case b = <-r.free:
+	default:
+		if r.made < r.n {
+			r.made++
+			b = &block{}
+			break
+		}
+		select {
+		case b = <-r.free:
+		case <-stop:
+			return nil, false
+		}
+	}
+	if !r.mem.acquire(stop) {
+		r.free <- b
+		return nil, false
+	}
+	b.in = b.in[:0]
+	b.out.Reset()
+	b.err = nil
+	b.done = make(chan struct{})
+	return b, true
+}
+
+// reserve makes room in the input of b for n bytes in all, growing it
+// by doubling up to size
+func reserve(b *block, n, size int) {
+	if cap(b.in) >= n {
+		return
+	}
+	c := 2 * cap(b.in)
+	if c < minSlot {
+		c = minSlot
+	}
+	if c < n {
+		c = n
+	}
+	if c > size {
+		c = size
+	}
+	in := make([]byte, len(b.in), c)
+	copy(in, b.in)
+	b.in = in
+}
+
+// put hands a slot back once its output has been written
+func (r *ring) put(b *block) {
+	r.mem.release()
+	r.free <- b
+}
+
+// errWriterClosed is returned by a ParallelWriter used after Close
+var errWriterClosed = errors.New("bz: write after Close")
+
+// ErrVerify means a block compressed with Config.Verify set didn't
+// decode back to its input; nothing of it was written
+var ErrVerify = errors.New("bz: compressed block doesn't decode to its input")
+
+// Config controls a ParallelWriter
+type Config struct {
+	Level     int          // 1 (fastest) to 9 (best, the default)
+	Workers   int          // blocks compressed at once, all CPUs if 0
+	BlockSize int          // input bytes per stream, one block's worth if 0
+	Memory    *MemoryLimit // bounds the blocks held in memory, if set
+
+	// Verify decodes each compressed block again and checks it
+	// against the input before it's written, taking up to twice the
+	// CPU time, for archives that must be known good
+	Verify bool
+
+	// Trace, if set, receives diagnostics at the levels of Options
+	Trace func(level int, format string, a ...interface{})
+
+	// Progress, if set, is called after each block is written
+	Progress func(Progress)
+}
+
+func (c *Config) tracef(level int, format string, a ...interface{}) {
+	if c.Trace != nil {
+		c.Trace(level, format, a...)
+	}
+}
+
+// config returns the Config compression with o uses
+func (o *Options) config() *Config {
+	c := &Config{Level: o.level(), Workers: o.workers(), Memory: o.memory(), Progress: o.progress()}
+	if o != nil {
+		c.Trace, c.Verify = o.Trace, o.Verify
+	}
+	return c
+}
+
+// A ParallelWriter compresses the data written to it as a sequence of
+// independent bzip2 streams, one per block, which any bzip2 decoder
+// reads back as a single multi-stream file, as pbzip2 does.
+//
+// Writes fill ring slots, which workers compress concurrently while a
+// goroutine of its own writes finished blocks to the underlying writer
+// in order, so slow storage overlaps with compression instead of
+// stalling it. Close must be called to compress the last block; it
+// doesn't close the underlying writer.
+//
+// Once the context of NewWriterContext is done, blocks are neither
+// compressed nor written any more, and Write and Close return its
+// error; what was written before stays, for the caller to remove.
+type ParallelWriter struct {
+	w     io.Writer
+	cfg   Config
+	rg    *ring
+	cur   *block      // slot being filled, if any
+	work  chan *block // blocks waiting for a worker
+	order chan *block // blocks in input order
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once // guards err and stop
+	fit   bool      // cut blocks to what a single one holds
+
+	// err is set before stop is closed, the rest by the output
+	// goroutine before done is
+	err       error
+	nin, nout int64
+	blocks    int
+
+	closed   bool
+	closeErr error
+}
+
+// NewParallelWriter returns a ParallelWriter compressing into w as
+// set by cfg, which may be nil for the defaults
+func NewParallelWriter(w io.Writer, cfg *Config) (*ParallelWriter, error) {
+	return NewWriterContext(context.Background(), w, cfg)
+}
+
+// NewWriterContext is NewParallelWriter with a context cancelling the
+// compression
+func NewWriterContext(ctx context.Context, w io.Writer, cfg *Config) (*ParallelWriter, error) {
+	var c Config
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.Level == 0 {
+		c.Level = 9
+	}
+	if c.Level < 1 || c.Level > 9 {
+		return nil, fmt.Errorf("bz: invalid compression level %d", c.Level)
+	}
+	if c.Workers < 1 {
+		c.Workers = runtime.NumCPU()
+	}
+	if c.BlockSize < 0 {
+		return nil, fmt.Errorf("bz: invalid block size %d", c.BlockSize)
+	}
+	fit := c.BlockSize == 0
+	if fit {
+		c.BlockSize = c.Level * BlockSize
+	}
+
+	workers := c.Memory.maxBlocks(c.Workers)
+	if workers < 1 {
+		workers = 1
+	}
+	slots := c.Memory.maxBlocks(2 * workers)
+	p := &ParallelWriter{
+		w:     w,
+		cfg:   c,
+		rg:    newRing(slots, c.Memory),
+		work:  make(chan *block, slots),
+		order: make(chan *block, slots),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+		fit:   fit,
+	}
+	c.tracef(TraceDebug, "    compress: level %d, %d workers, %d slots of %d bytes\n",
+		c.Level, workers, slots, c.BlockSize)
+	for i := 0; i < workers; i++ {
+		go p.compressBlocks()
+	}
+	go p.writeBlocks()
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				p.fail(ctx.Err())
+			case <-p.done:
+			}
+		}()
+	}
+	return p, nil
+}
+
+// fail stops the pipeline with err, unless it's stopped or finished
+// already
+func (p *ParallelWriter) fail(err error) {
+	p.once.Do(func() {
+		p.err = err
+		close(p.stop)
+	})
+}
+
+// stopped tells whether the pipeline was stopped by an error
+func (p *ParallelWriter) stopped() bool {
+	select {
+	case <-p.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// compressBlocks is a worker of the compression stage
+func (p *ParallelWriter) compressBlocks() {
+	level := p.cfg.Level
+	var zr *bzip2.Reader
+	if p.cfg.Verify {
+		zr, _ = getReader(nil)
+		defer putReader(zr)
+	}
+	for b := range p.work {
+		if p.stopped() {
+			close(b.done)
+			continue
+		}
+		start := time.Now()
+		b.err = compressBlock(b, level, zr)
+		b.took = time.Since(start)
+		close(b.done)
+	}
+}
+
+// compressBlock compresses the input of b into its output, checking it
+// with zr if set
+func compressBlock(b *block, level int, zr *bzip2.Reader) (err error) {
+	defer recoverPanic(&err)
+	z, err := getWriter(&b.out, level)
+	if err == nil {
+		_, err = z.Write(b.in)
+		if err == nil {
+			err = z.Close()
+		}
+		if err == nil {
+			putWriter(z, level)
+		}
+	}
+	if err == nil && zr != nil {
+		err = verifyBlock(zr, b)
+	}
+	return err
+}
+
+// verifyBlock decodes the stream of b with z, checking that it gives
+// back the input and, through the decoder, the CRCs
+func verifyBlock(z *bzip2.Reader, b *block) error {
+	if err := z.Reset(bytes.NewReader(b.out.Bytes())); err != nil {
+		return err
+	}
+	m := &matchWriter{want: b.in}
+	_, err := io.Copy(m, z)
+	if err == nil {
+		err = z.Close()
+	}
+	switch {
+	case err == ErrVerify:
+		return err
+	case err != nil:
+		return fmt.Errorf("%w: %v", ErrVerify, err)
+	case len(m.want) > 0:
+		return ErrVerify
+	}
+	return nil
+}
+
+// matchWriter fails with ErrVerify as soon as what is written to it
+// differs from want
+type matchWriter struct {
+	want []byte // not matched yet
+}
+
+func (m *matchWriter) Write(p []byte) (int, error) {
+	if len(p) > len(m.want) || !bytes.Equal(p, m.want[:len(p)]) {
+		return 0, ErrVerify
+	}
+	m.want = m.want[len(p):]
+	return len(p), nil
+}
+
+// writeBlocks is the output stage: it emits blocks in input order
+func (p *ParallelWriter) writeBlocks() {
+	defer close(p.done)
+	defer p.once.Do(func() {}) // no failure past the end
+	for b := range p.order {
+		<-b.done
+		if !p.stopped() {
+			err := b.err
+			if err == nil {
+				var n int
+				n, err = p.w.Write(b.out.Bytes())
+				p.nout += int64(n)
+				p.nin += int64(len(b.in))
+				p.cfg.tracef(TraceBlocks, "    block %d: %d in, %d out, %v\n",
+					p.blocks+1, len(b.in), n, b.took)
+			}
+			if err != nil {
+				p.fail(err)
+			} else if p.cfg.Progress != nil {
+				p.cfg.Progress(Progress{In: p.nin, Out: p.nout, Blocks: p.blocks + 1})
+			}
+		}
+		p.blocks++
+		p.rg.put(b)
+	}
+}
+
+// slot returns the slot being filled, waiting for a free one if
+// needed; it fails once the output stage gave up
+func (p *ParallelWriter) slot() (*block, bool) {
+	if p.stopped() {
+		return nil, false
+	}
+	if p.cur == nil {
+		b, ok := p.rg.get(p.stop)
+		if !ok {
+			return nil, false
+		}
+		p.cur = b
+	}
+	return p.cur, true
+}
+
+// room returns where the next input goes in the slot cur
+func (p *ParallelWriter) room(cur *block) []byte {
+	reserve(cur, len(cur.in)+1, p.cfg.BlockSize)
+	return cur.in[len(cur.in):cap(cur.in)]
+}
+
+// full reports whether the slot cur holds a whole block of input
+func (p *ParallelWriter) full(cur *block) bool {
+	return len(cur.in) == p.cfg.BlockSize
+}
+
+// dispatch hands the slot being filled to the workers, and to the
+// output stage. With fit set, the input beyond what a block holds is
+// carried over to the next slot, so that each stream is one block
+func (p *ParallelWriter) dispatch() {
+	b := p.cur
+	p.cur = nil
+	var rest []byte
+	if p.fit {
+		n := rle1Fit(b.in, p.cfg.Level*BlockSize)
+		b.in, rest = b.in[:n], b.in[n:]
+	}
+	p.order <- b
+	p.work <- b
+	if len(rest) > 0 {
+		// The worker only reads b.in, before rest: the slot stays
+		// out of the ring until its block is written, and should
+		// it come back as the next one, copy moves rest down
+		if next, ok := p.slot(); ok {
+			reserve(next, len(rest), p.cfg.BlockSize)
+			next.in = next.in[:len(rest)]
+			copy(next.in, rest)
+		}
+	}
+}
+
+// rle1Fit returns how many bytes of p the encoder puts into a block of
+// capacity bytes after its first run-length coding, which turns runs
+// of 4 to 255 equal bytes into 4 and a count. Runs of exactly 4 grow,
+// so a block may not take a whole slot
+func rle1Fit(p []byte, capacity int) int {
+	n, run := 0, 0
+	for i, c := range p {
+		if i == 0 || c != p[i-1] {
+			run = 0
+		}
+		run++
+		switch {
+		case run < 4:
+			if n >= capacity {
+				return i
+			}
+			n++
+		case run == 4:
+			if n+1 >= capacity {
+				return i
+			}
+			n += 2
+		case run < 256:
+		default:
+			if n >= capacity {
+				return i
+			}
+			run = 1
+			n++
+		}
+	}
+	return len(p)
+}
+
+// Write compresses the data of b, once a block of it is complete. An
+// error writing to the underlying writer is returned by the writes
+// that follow it, and by Close
+func (p *ParallelWriter) Write(b []byte) (int, error) {
+	if p.closed {
+		return 0, errWriterClosed
+	}
+	n := 0
+	for len(b) > 0 {
+		cur, ok := p.slot()
+		if !ok {
+			return n, p.err
+		}
+		m := copy(p.room(cur), b)
+		cur.in = cur.in[:len(cur.in)+m]
+		n += m
+		b = b[m:]
+		if p.full(cur) {
+			p.dispatch()
+		}
+	}
+	return n, nil
+}
+
+// ReadFrom compresses the data of r until its end, reading it
+// straight into the blocks instead of copying it as Write does; so
+// io.Copy to a ParallelWriter needs no buffer of its own. It returns
+// the number of bytes read, and errors as Write does
+func (p *ParallelWriter) ReadFrom(r io.Reader) (int64, error) {
+	if p.closed {
+		return 0, errWriterClosed
+	}
+	var total int64
+	for {
+		cur, ok := p.slot()
+		if !ok {
+			return total, p.err
+		}
+		n, err := io.ReadFull(r, p.room(cur))
+		cur.in = cur.in[:len(cur.in)+n]
+		total += int64(n)
+		if p.full(cur) {
+			p.dispatch()
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// Close compresses the last block and waits until all of them are
+// written. Empty input still produces a valid, empty, stream. Further
+// calls return the same result
+func (p *ParallelWriter) Close() error {
+	if p.closed {
+		return p.closeErr
+	}
+	p.closed = true
+	for p.cur != nil && len(p.cur.in) > 0 {
+		p.dispatch() // may leave the rest in p.cur
+	}
+	if p.cur != nil {
+		p.rg.put(p.cur)
+		p.cur = nil
+	}
+	close(p.work)
+	close(p.order)
+	<-p.done
+
+	err := p.err
+	if err == nil && p.blocks == 0 {
+		cw := &countWriter{w: p.w}
+		z, zerr := getWriter(cw, p.cfg.Level)
+		if zerr == nil {
+			if zerr = z.Close(); zerr == nil {
+				putWriter(z, p.cfg.Level)
+			}
+		}
+		err, p.nout = zerr, cw.n
+	}
+	p.closeErr = err
+	return err
+}
+
+// Compress compresses r into w with a ParallelWriter, reading the
+// input straight into its blocks. It returns the number of bytes read
+// and written
+func Compress(w io.Writer, r io.Reader, opts *Options) (nin, nout int64, err error) {
+	return compress(context.Background(), w, r, opts)
+}
+
+func compress(ctx context.Context, w io.Writer, r io.Reader, opts *Options) (nin, nout int64, err error) {
+	p, err := NewWriterContext(ctx, w, opts.config())
+	if err != nil {
+		return 0, 0, err
+	}
+	_, rerr := p.ReadFrom(r)
+	if err = p.Close(); err == nil {
+		err = rerr
+	}
+	return p.nin, p.nout, err
+}
+
+// countWriter counts the bytes written through it
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
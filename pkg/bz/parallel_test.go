@@ -0,0 +1,174 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// runs returns n bytes of runs of the given length, which the first
+// run-length coding of the encoder grows or shrinks
+func runs(n, length int) []byte {
+	p := make([]byte, n)
+	for i := range p {
+		p[i] = byte(i / length)
+	}
+	return p
+}
+
+// roundTrips are data and the configurations they are compressed with
+var roundTrips = []struct {
+	name string
+	data []byte
+	cfg  *Config
+}{
+	{"empty", nil, nil},
+	{"one byte", []byte{'x'}, nil},
+	{"text", testData(250000), &Config{Level: 1, Workers: 2}},
+	{"runs of 4", runs(250000, 4), &Config{Level: 1, Workers: 3}},
+	{"zeros", make([]byte, 1000000), &Config{Level: 1, Workers: 2}},
+	{"small blocks", testData(50000), &Config{Level: 9, Workers: 2, BlockSize: 7000}},
+	{"one block in memory", testData(300000), &Config{Level: 1, Workers: 4, Memory: NewMemoryLimit(1, 1)}},
+}
+
+func TestParallelWriterRoundTrip(t *testing.T) {
+	for _, tt := range roundTrips {
+		z := compressTest(t, tt.data, tt.cfg)
+		if got := decompressSerial(t, z); !bytes.Equal(got, tt.data) {
+			t.Errorf("%s: decoded %d bytes, want %d", tt.name, len(got), len(tt.data))
+		}
+	}
+}
+
+// Writes of any size make the same streams
+func TestParallelWriterSmallWrites(t *testing.T) {
+	data := testData(230000)
+	cfg := &Config{Level: 1, Workers: 2}
+	want := compressTest(t, data, cfg)
+
+	var z bytes.Buffer
+	p, err := NewParallelWriter(&z, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rest := data; len(rest) > 0; {
+		m := 777
+		if m > len(rest) {
+			m = len(rest)
+		}
+		if _, err := p.Write(rest[:m]); err != nil {
+			t.Fatal(err)
+		}
+		rest = rest[m:]
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(z.Bytes(), want) {
+		t.Errorf("small writes and one write compress differently")
+	}
+	if _, err := p.Write([]byte{0}); err == nil {
+		t.Errorf("Write after Close succeeded")
+	}
+	if err := p.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+// ReadFrom, reading into the blocks, makes the same streams as Write
+func TestParallelWriterReadFrom(t *testing.T) {
+	for _, tt := range roundTrips {
+		want := compressTest(t, tt.data, tt.cfg)
+		var z bytes.Buffer
+		p, err := NewParallelWriter(&z, tt.cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Through io.Copy, which should find ReadFrom
+		n, err := io.Copy(p, onlyReader{bytes.NewReader(tt.data)})
+		if err != nil || n != int64(len(tt.data)) {
+			t.Fatalf("%s: ReadFrom = %d, %v, want %d", tt.name, n, err, len(tt.data))
+		}
+		if err := p.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(z.Bytes(), want) {
+			t.Errorf("%s: ReadFrom and Write compress differently", tt.name)
+		}
+	}
+}
+
+// With the default block size, each stream holds one block, however
+// the data grows through the first run-length coding
+func TestParallelWriterOneBlock(t *testing.T) {
+	for _, data := range [][]byte{testData(250000), runs(250000, 4), runs(250000, 5), make([]byte, 300000)} {
+		z := compressTest(t, data, &Config{Level: 1})
+		it := Streams(bytes.NewReader(z))
+		n := 0
+		for it.Next() {
+			if s := it.Stream(); s.Blocks != 1 {
+				t.Errorf("stream at %d has %d blocks, want 1", s.Offset, s.Blocks)
+			}
+			n++
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if n < 2 {
+			t.Errorf("%d streams, want several", n)
+		}
+	}
+}
+
+func TestRLE1Fit(t *testing.T) {
+	tests := []struct {
+		p        []byte
+		capacity int
+		want     int
+	}{
+		{nil, 10, 0},
+		{[]byte("abc"), 10, 3},
+		{[]byte("abcd"), 3, 3},
+		{[]byte("aaaa"), 5, 4},
+		{[]byte("aaaab"), 5, 4}, // aaaa takes 5
+		{[]byte("aaaab"), 6, 5},
+		{[]byte("aaaa"), 4, 3}, // no room for the count
+		{bytes.Repeat([]byte("a"), 255), 5, 255},
+		{bytes.Repeat([]byte("a"), 256), 5, 255},
+		{bytes.Repeat([]byte("a"), 256), 6, 256},
+	}
+	for _, tt := range tests {
+		if got := rle1Fit(tt.p, tt.capacity); got != tt.want {
+			t.Errorf("rle1Fit(%q, %d) = %d, want %d", tt.p, tt.capacity, got, tt.want)
+		}
+	}
+}
+
+// A small input takes one small slot, however many workers there are
+func TestParallelWriterSlots(t *testing.T) {
+	var z bytes.Buffer
+	p, err := NewParallelWriter(&z, &Config{Level: 9, Workers: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if p.rg.made != 1 || cap(p.cur.in) > minSlot {
+		t.Errorf("%d slots of %d bytes, want 1 of at most %d", p.rg.made, cap(p.cur.in), minSlot)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// onlyReader hides the methods of a reader besides Read, so io.Copy
+// can't use its WriteTo
+type onlyReader struct{ r io.Reader }
+
+func (o onlyReader) Read(p []byte) (int, error) { return o.r.Read(p) }
@@ -0,0 +1,64 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/dsnet/compress/bzip2"
+)
+
+// writerPools keeps idle encoders, one pool per level, so that blocks
+// and files don't each allocate a fresh set of encoder tables
+var writerPools [bzip2.BestCompression + 1]sync.Pool
+
+// getWriter returns an encoder at level writing to w, reusing an idle
+// one through Reset when possible
+func getWriter(w io.Writer, level int) (*bzip2.Writer, error) {
+	if z, ok := writerPools[level].Get().(*bzip2.Writer); ok {
+		if err := z.Reset(w); err != nil {
+			return nil, err
+		}
+		return z, nil
+	}
+	return bzip2.NewWriter(w, &bzip2.WriterConfig{Level: level})
+}
+
+// putWriter hands back an encoder obtained from getWriter; it must
+// have been closed
+func putWriter(z *bzip2.Writer, level int) {
+	z.Reset(nil) // drop the reference to the last output
+	writerPools[level].Put(z)
+}
+
+// readerPool keeps idle decoders, so that -t and -d over many files or
+// blocks don't allocate fresh decode tables each time
+var readerPool sync.Pool
+
+// getReader returns a decoder reading from r, reusing an idle one
+// through Reset when possible
+func getReader(r io.Reader) (*bzip2.Reader, error) {
+	if z, ok := readerPool.Get().(*bzip2.Reader); ok {
+		if err := z.Reset(r); err != nil {
+			return nil, err
+		}
+		return z, nil
+	}
+	return bzip2.NewReader(r, nil)
+}
+
+// putReader hands back a decoder obtained from getReader. A decoder in
+// an error state is fine: Reset clears it. Reset keeps what the last
+// block decoded left unread, though, which the next stream would
+// start with, so that is drained first
+func putReader(z *bzip2.Reader) {
+	z.Reset(strings.NewReader(""))
+	io.Copy(io.Discard, z)
+	z.Reset(nil) // drop the reference to the last input
+	readerPool.Put(z)
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// A decoder given back with data left unread doesn't hand it out
+// again from the next stream
+func TestReaderReuse(t *testing.T) {
+	long := compressTest(t, bytes.Repeat([]byte("A"), 100000), &Config{Level: 1})
+	short := compressTest(t, []byte("hello"), &Config{Level: 1})
+	for i := 0; i < 3; i++ {
+		r, err := NewReader(bytes.NewReader(long))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Read(make([]byte, 10)); err != nil {
+			t.Fatal(err)
+		}
+		r.Close()
+		if got := decompressSerial(t, short); string(got) != "hello" {
+			t.Fatalf("decoder reused after a partial read gave %d bytes, want %q", len(got), "hello")
+		}
+	}
+
+	// So with corrupt data, and with streams run through directly
+	z, _ := getReader(bytes.NewReader(long[:len(long)/2]))
+	io.Copy(io.Discard, z)
+	putReader(z)
+	z, _ = getReader(bytes.NewReader(short))
+	got, err := io.ReadAll(z)
+	putReader(z)
+	if err != nil || string(got) != "hello" {
+		t.Errorf("decoder reused after truncated data gave %d bytes, %v, want %q", len(got), err, "hello")
+	}
+}
@@ -0,0 +1,530 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/dsnet/compress/bzip2"
+)
+
+// A ParallelReader finds the blocks of a stream of compressed data as
+// it reads it, the way ScanLayout does for a file, and decodes them as
+// in DecompressAt. Without an io.ReaderAt, the structure can't be
+// checked up front: a footer magic ends its stream only when the end
+// of the data, another stream header or nothing that looks like a
+// block follows it, and damage is reported when the blocks around it
+// are read, after the data before it.
+
+const (
+	// readChunk is how much input the scanner reads at a time
+	readChunk = 1 << 20
+
+	// maxBlockBytes bounds the compressed size of a block: 900k of
+	// input and the worst expansion of its coding. A footer magic
+	// that another magic follows within it lies inside a block
+	maxBlockBytes = 2 << 20
+)
+
+// ReaderConfig controls a ParallelReader
+type ReaderConfig struct {
+	Workers int          // blocks decoded at once, all CPUs if 0
+	Memory  *MemoryLimit // bounds the blocks held in memory, if set
+
+	// Trace, if set, receives diagnostics at the levels of Options
+	Trace func(level int, format string, a ...interface{})
+
+	// Progress, if set, is called by Read as each block is reached
+	Progress func(Progress)
+}
+
+func (c *ReaderConfig) tracef(level int, format string, a ...interface{}) {
+	if c.Trace != nil {
+		c.Trace(level, format, a...)
+	}
+}
+
+// readJob is a block candidate found by the scanner, with its bits
+// kept so a failed candidate can be merged with the following ones
+type readJob struct {
+	start     int64  // input bit offset of the magic
+	bits      []byte // shifted to start at bit 0
+	nbits     int64
+	crc       uint32 // block CRC as stored after the magic
+	last      bool   // last candidate of its stream
+	streamCRC uint32 // stored combined CRC of the stream, if last
+	next      int64  // input offset the candidate ends at, footer included
+	fail      error  // the scan stopped here, nothing to decode
+
+	out  bytes.Buffer
+	err  error
+	took time.Duration // time spent decoding
+	done chan struct{}
+}
+
+// decodeJobs decodes the candidates of jobs merged into a single
+// block, whose CRC is crc, into out
+func decodeJobs(z *bzip2.Reader, jobs []*readJob, crc uint32, out *bytes.Buffer) (err error) {
+	defer recoverPanic(&err)
+	parts := make([][]byte, len(jobs))
+	nbits := make([]int64, len(jobs))
+	for i, j := range jobs {
+		parts[i], nbits[i] = j.bits, j.nbits
+	}
+	if err := z.Reset(bytes.NewReader(joinBlocks(parts, nbits, crc))); err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, z); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+// A ParallelReader decompresses the bzip2 data read from an io.Reader,
+// decoding its blocks concurrently, those of multi-stream files made
+// by pbzip2 or a ParallelWriter as well as those of stock bzip2 files.
+//
+// A goroutine reads the input ahead, finding blocks that workers
+// decode while Read returns the finished ones in order, checking the
+// combined CRC of each stream. Close releases the goroutines; it
+// doesn't close the underlying reader.
+//
+// Once the context of NewReaderContext is done, blocks are no longer
+// decoded and Read returns its error.
+type ParallelReader struct {
+	ctx    context.Context
+	cfg    ReaderConfig
+	work   chan *readJob // candidates waiting for a worker
+	order  chan *readJob // candidates in input order
+	window chan struct{} // bounds candidates not yet taken by Read
+	stop   chan struct{}
+	halt   sync.Once // closes stop
+
+	ahead    []*readJob // taken from order, not consumed yet
+	cur      []byte     // output of the block being read
+	combined uint32     // CRC of the stream so far
+	blocks   int
+	nout     int64
+	index    *Index        // gets the blocks, for BuildIndex
+	z        *bzip2.Reader // merges failed candidates
+	err      error
+	stopped  bool
+	closed   bool
+}
+
+// NewParallelReader returns a ParallelReader decompressing the data of
+// r as set by cfg, which may be nil for the defaults
+func NewParallelReader(r io.Reader, cfg *ReaderConfig) *ParallelReader {
+	return NewReaderContext(context.Background(), r, cfg)
+}
+
+// NewReaderContext is NewParallelReader with a context cancelling the
+// decompression
+func NewReaderContext(ctx context.Context, r io.Reader, cfg *ReaderConfig) *ParallelReader {
+	var c ReaderConfig
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.Workers < 1 {
+		c.Workers = runtime.NumCPU()
+	}
+	workers := c.Memory.maxBlocks(c.Workers)
+	if workers < 1 {
+		workers = 1
+	}
+	slots := c.Memory.maxBlocks(2 * workers)
+	p := &ParallelReader{
+		ctx:    ctx,
+		cfg:    c,
+		work:   make(chan *readJob, slots),
+		order:  make(chan *readJob, slots),
+		window: make(chan struct{}, slots),
+		stop:   make(chan struct{}),
+	}
+	c.tracef(TraceDebug, "    decode: %d workers, %d slots\n", workers, slots)
+	for i := 0; i < workers; i++ {
+		go p.decodeBlocks()
+	}
+	go p.scan(&blockScanner{r: r, trace: c.Trace})
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				p.halt.Do(func() { close(p.stop) })
+			case <-p.stop:
+			}
+		}()
+	}
+	return p
+}
+
+// decodeBlocks is a worker of the decode stage
+func (p *ParallelReader) decodeBlocks() {
+	z, _ := getReader(nil)
+	defer putReader(z)
+	for j := range p.work {
+		select {
+		case <-p.stop:
+			j.err = errClosed
+		default:
+			start := time.Now()
+			j.err = decodeJobs(z, []*readJob{j}, j.crc, &j.out)
+			j.took = time.Since(start)
+		}
+		close(j.done)
+	}
+}
+
+// send hands j to the workers and to Read, once it fits in the window
+// and the memory limit; it fails when the reader is closed
+func (p *ParallelReader) send(j *readJob) bool {
+	select {
+	case p.window <- struct{}{}:
+	case <-p.stop:
+		return false
+	}
+	if !p.cfg.Memory.acquire(p.stop) {
+		<-p.window
+		return false
+	}
+	j.done = make(chan struct{})
+	p.order <- j
+	if j.fail != nil {
+		close(j.done)
+	} else {
+		p.work <- j
+	}
+	return true
+}
+
+// scan is the input stage: it splits the streams of s into candidates.
+// An error ends the input as a candidate of its own, so the blocks
+// before it are still read
+func (p *ParallelReader) scan(s *blockScanner) {
+	defer close(p.order)
+	defer close(p.work)
+	if err := p.scanStreams(s); err != nil {
+		p.send(&readJob{fail: err})
+	}
+}
+
+func (p *ParallelReader) scanStreams(s *blockScanner) (err error) {
+	defer recoverPanic(&err)
+	// candidate sends the candidate [start, end), reading the CRC
+	// after its magic
+	candidate := func(start, end int64, last bool, streamCRC uint32) error {
+		bits, err := ReadBits(s, start, end)
+		if err != nil {
+			return err
+		}
+		j := &readJob{start: start, bits: bits, nbits: end - start, last: last, streamCRC: streamCRC, next: end / 8}
+		if last {
+			j.next = (end + 80 + 7) / 8
+		}
+		if crc, err := ReadBits(s, start+48, start+80); err == nil {
+			j.crc = binary.BigEndian.Uint32(crc)
+		}
+		if !p.send(j) {
+			return errClosed
+		}
+		return nil
+	}
+
+	pos := int64(0) // byte offset of the next stream header
+	for streams := 0; ; streams++ {
+		st, err := s.stream(pos, candidate)
+		if err == io.EOF {
+			if streams > 0 {
+				return nil
+			}
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+		pos = st.Offset + st.Size
+	}
+}
+
+// take returns the candidate k places after the one being read,
+// waiting for it, or nil at the end of the input. Its share of the
+// window and memory limit is released once it leaves order, so the
+// scanner can run ahead of a merge
+func (p *ParallelReader) take(k int) *readJob {
+	for len(p.ahead) <= k {
+		j, ok := <-p.order
+		if !ok {
+			return nil
+		}
+		p.cfg.Memory.release()
+		<-p.window
+		p.ahead = append(p.ahead, j)
+	}
+	return p.ahead[k]
+}
+
+// next makes the output of the next block current
+func (p *ParallelReader) next() error {
+	j := p.take(0)
+	if j == nil {
+		return io.EOF
+	}
+	<-j.done
+	if j.fail != nil {
+		return j.fail
+	}
+	out, n := j.out.Bytes(), 1
+	if j.err != nil {
+		// A magic found by chance may have split the block: try
+		// again together with the following candidates
+		err := j.err
+		if p.z == nil {
+			p.z, _ = getReader(nil)
+		}
+		for k := 1; k <= maxMerge && !p.ahead[k-1].last; k++ {
+			if nj := p.take(k); nj == nil || nj.fail != nil {
+				break
+			}
+			var merged bytes.Buffer
+			if decodeJobs(p.z, p.ahead[:k+1], j.crc, &merged) == nil {
+				p.cfg.tracef(TraceDebug, "    decode: candidates %d-%d merged into one block\n",
+					p.blocks+1, p.blocks+k+1)
+				out, n, err = merged.Bytes(), k+1, nil
+				break
+			}
+		}
+		if err != nil {
+			return blockError(err, p.blocks+1, j.crc, j.out.Bytes())
+		}
+	}
+	p.combined = CombineCRC(p.combined, j.crc)
+	last := p.ahead[n-1]
+	if last.last {
+		if p.combined != last.streamCRC {
+			return &ErrCRCMismatch{Block: p.blocks + 1, Stream: true, Want: last.streamCRC, Got: p.combined}
+		}
+		p.combined = 0
+	}
+	if p.index != nil {
+		p.index.Blocks = append(p.index.Blocks, IndexBlock{
+			Start: j.start, End: last.start + last.nbits, Offset: p.nout, CRC: j.crc,
+		})
+		p.index.CompressedSize = last.next
+	}
+	p.blocks++
+	p.nout += int64(len(out))
+	if p.cfg.Progress != nil {
+		p.cfg.Progress(Progress{In: last.next, Out: p.nout, Blocks: p.blocks})
+	}
+	p.cfg.tracef(TraceBlocks, "    block %d: crc = 0x%08x, %d out, %v\n",
+		p.blocks, j.crc, len(out), j.took)
+	p.ahead = append(p.ahead[:0], p.ahead[n:]...)
+	p.cur = out
+	return nil
+}
+
+func (p *ParallelReader) Read(b []byte) (int, error) {
+	if err := p.advance(); err != nil {
+		return 0, err
+	}
+	n := copy(b, p.cur)
+	p.cur = p.cur[n:]
+	return n, nil
+}
+
+// WriteTo writes the decompressed data to w until its end, straight
+// from the output of the blocks, so io.Copy from a ParallelReader
+// needs no buffer of its own. It returns the number of bytes written
+func (p *ParallelReader) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for {
+		if err := p.advance(); err == io.EOF {
+			return total, nil
+		} else if err != nil {
+			return total, err
+		}
+		n, err := w.Write(p.cur)
+		total += int64(n)
+		p.cur = p.cur[n:]
+		if err == nil && len(p.cur) > 0 {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// advance makes the output of the next block current once that of
+// the current one is used up, or returns the error ending the data
+func (p *ParallelReader) advance() error {
+	if p.closed {
+		return errClosed
+	}
+	if p.err == nil && p.ctx.Err() != nil {
+		p.err = p.ctx.Err()
+		p.shutdown()
+	}
+	for len(p.cur) == 0 || p.err != nil {
+		if p.err != nil {
+			return p.err
+		}
+		if p.err = p.next(); p.err != nil {
+			// A cancelled scan ends early, or with errClosed
+			if err := p.ctx.Err(); err != nil {
+				p.err = err
+			}
+			if p.err != io.EOF {
+				p.shutdown()
+			}
+		}
+	}
+	return nil
+}
+
+// shutdown stops the scanner and the workers, and drains the
+// candidates left so they release the memory limit
+func (p *ParallelReader) shutdown() {
+	if p.stopped {
+		return
+	}
+	p.stopped = true
+	p.halt.Do(func() { close(p.stop) })
+	go func() {
+		for range p.order {
+			p.cfg.Memory.release()
+			<-p.window
+		}
+	}()
+}
+
+// Close reports any error of the data read so far, and releases the
+// goroutines; one blocked reading the underlying reader ends when
+// that read returns. Further calls return the same result
+func (p *ParallelReader) Close() error {
+	if !p.closed {
+		p.closed = true
+		p.shutdown()
+		p.ahead, p.cur = nil, nil
+		if p.z != nil {
+			putReader(p.z)
+			p.z = nil
+		}
+	}
+	if p.err == io.EOF {
+		return nil
+	}
+	return p.err
+}
+
+// blockScanner reads the input ahead of the candidates, finding the
+// magics in it as ScanMarks does
+type blockScanner struct {
+	r       io.Reader
+	buf     []byte
+	base    int64  // input offset of buf[0]
+	scanned int64  // magics starting before this offset are in marks
+	marks   []Mark // found and not passed yet
+	eof     bool
+
+	// trace, if set, receives diagnostics at the levels of Options
+	trace func(level int, format string, a ...interface{})
+}
+
+// more reads another chunk of input and finds the magics it
+// completes; it returns false at the end of the input
+func (s *blockScanner) more() (bool, error) {
+	if s.eof {
+		return false, nil
+	}
+	if len(s.buf)+readChunk+8 > cap(s.buf) {
+		buf := make([]byte, len(s.buf), 2*len(s.buf)+readChunk+8)
+		copy(buf, s.buf)
+		s.buf = buf
+	}
+	n, err := s.r.Read(s.buf[len(s.buf) : len(s.buf)+readChunk])
+	s.buf = s.buf[:len(s.buf)+n]
+	if err == io.EOF {
+		s.eof = true
+	} else if err != nil {
+		return false, err
+	}
+
+	// Magics need the 8 bytes from their first one; at the end the
+	// missing ones read as zeros, and magics that don't fit are
+	// dropped
+	end := s.base + int64(len(s.buf))
+	limit := end - 8
+	if s.eof {
+		limit = end
+		copy(s.buf[len(s.buf):cap(s.buf)], make([]byte, 8))
+	}
+	if limit > s.scanned {
+		k := len(s.marks)
+		s.marks = findMarks(s.marks, s.buf[:len(s.buf)+8], int(s.scanned-s.base), int(limit-s.base), s.base)
+		for len(s.marks) > k && s.marks[len(s.marks)-1].Bit+48 > end*8 {
+			s.marks = s.marks[:len(s.marks)-1]
+		}
+		s.scanned = limit
+	}
+	return true, nil
+}
+
+// nextMark returns the first magic at or after bit after, and before
+// bit before unless it's negative; it reports false if there's none
+func (s *blockScanner) nextMark(after, before int64) (Mark, bool, error) {
+	for {
+		for len(s.marks) > 0 && s.marks[0].Bit < after {
+			s.marks = s.marks[1:]
+		}
+		if len(s.marks) > 0 {
+			m := s.marks[0]
+			return m, before < 0 || m.Bit < before, nil
+		}
+		if before >= 0 && s.scanned*8 >= before {
+			return Mark{}, false, nil
+		}
+		if ok, err := s.more(); !ok {
+			return Mark{}, false, err
+		}
+	}
+}
+
+// ReadAt reads the input at off, which must not have been discarded,
+// reading more of it as needed
+func (s *blockScanner) ReadAt(b []byte, off int64) (int, error) {
+	for s.base+int64(len(s.buf)) < off+int64(len(b)) {
+		if ok, err := s.more(); err != nil {
+			return 0, err
+		} else if !ok {
+			break
+		}
+	}
+	n := 0
+	if i := off - s.base; i < int64(len(s.buf)) {
+		n = copy(b, s.buf[i:])
+	}
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// discard drops the input before offset off once it's a large part
+// of the buffer
+func (s *blockScanner) discard(off int64) {
+	if i := off - s.base; i > readChunk && i > int64(len(s.buf)/2) {
+		n := copy(s.buf, s.buf[i:])
+		s.buf = s.buf[:n]
+		s.base = off
+	}
+}
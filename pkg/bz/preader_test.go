@@ -0,0 +1,85 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestParallelReaderRoundTrip(t *testing.T) {
+	for _, tt := range roundTrips {
+		z := compressTest(t, tt.data, tt.cfg)
+		for _, cfg := range []*ReaderConfig{nil, {Workers: 3}, {Workers: 2, Memory: NewMemoryLimit(1, 9)}} {
+			p := NewParallelReader(bytes.NewReader(z), cfg)
+			got, err := io.ReadAll(p)
+			if cerr := p.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil || !bytes.Equal(got, tt.data) {
+				t.Errorf("%s: ParallelReader with %+v: %d bytes, %v, want %d", tt.name, cfg, len(got), err, len(tt.data))
+			}
+		}
+	}
+}
+
+// Reads smaller than a block get the data in order, from streams of
+// one block and of several, as the reference bzip2 makes
+func TestParallelReaderSmallReads(t *testing.T) {
+	data := testData(250000)
+	for _, z := range [][]byte{
+		compressTest(t, data, &Config{Level: 1, Workers: 2}),
+		compressTest(t, data, &Config{Level: 1, BlockSize: len(data)}),
+	} {
+		p := NewParallelReader(bytes.NewReader(z), nil)
+		var got []byte
+		buf := make([]byte, 999)
+		for {
+			n, err := p.Read(buf)
+			got = append(got, buf[:n]...)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		p.Close()
+		if !bytes.Equal(got, data) {
+			t.Errorf("read %d bytes, want %d", len(got), len(data))
+		}
+	}
+}
+
+// Close stops the decoding of data that wasn't read
+func TestParallelReaderEarlyClose(t *testing.T) {
+	z := compressTest(t, testData(500000), &Config{Level: 1, Workers: 2})
+	p := NewParallelReader(bytes.NewReader(z), &ReaderConfig{Workers: 2})
+	if _, err := p.Read(make([]byte, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	if _, err := p.Read(make([]byte, 10)); err == nil {
+		t.Errorf("Read after Close succeeded")
+	}
+}
+
+// WriteTo hands the blocks straight to the writer, as Read does
+func TestParallelReaderWriteTo(t *testing.T) {
+	for _, tt := range roundTrips {
+		z := compressTest(t, tt.data, tt.cfg)
+		p := NewParallelReader(bytes.NewReader(z), nil)
+		var out bytes.Buffer
+		n, err := p.WriteTo(&out)
+		p.Close()
+		if err != nil || n != int64(len(tt.data)) || !bytes.Equal(out.Bytes(), tt.data) {
+			t.Errorf("%s: WriteTo = %d, %v, want %d", tt.name, n, err, len(tt.data))
+		}
+	}
+}
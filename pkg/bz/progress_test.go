@@ -0,0 +1,50 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// checkProgress checks that the reports ps only go forward and end at
+// in, out and blocks
+func checkProgress(t *testing.T, what string, ps []Progress, in, out int64, blocks int) {
+	t.Helper()
+	if len(ps) != blocks {
+		t.Errorf("%s: %d reports, want one per block, %d", what, len(ps), blocks)
+	}
+	var prev Progress
+	for _, p := range ps {
+		if p.In < prev.In || p.Out < prev.Out || p.Blocks != prev.Blocks+1 {
+			t.Errorf("%s: %+v after %+v", what, p, prev)
+		}
+		prev = p
+	}
+	if prev.In != in || prev.Out != out || prev.Blocks != blocks {
+		t.Errorf("%s: last report %+v, want %d in, %d out, %d blocks", what, prev, in, out, blocks)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	data := testData(350000)
+	var ps []Progress
+	z := compressTest(t, data, &Config{Level: 1, Workers: 2, Progress: func(p Progress) {
+		ps = append(ps, p)
+	}})
+	checkProgress(t, "ParallelWriter", ps, int64(len(data)), int64(len(z)), 4)
+
+	ps = nil
+	r := NewParallelReader(bytes.NewReader(z), &ReaderConfig{Workers: 2, Progress: func(p Progress) {
+		ps = append(ps, p)
+	}})
+	defer r.Close()
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		t.Fatal(err)
+	}
+	checkProgress(t, "ParallelReader", ps, int64(len(z)), int64(len(data)), 4)
+}
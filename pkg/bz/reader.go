@@ -0,0 +1,54 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"errors"
+	"io"
+
+	"github.com/dsnet/compress/bzip2"
+)
+
+// errClosed is returned by a Reader used after Close
+var errClosed = errors.New("bz: read after Close")
+
+// A Reader decompresses the bzip2 data read from an io.Reader
+// serially, as a sequence of one or more streams. Decoders are reused
+// across Readers, so Close should always be called
+type Reader struct {
+	z   *bzip2.Reader
+	n   int64 // bytes decoded
+	err error // result of Close
+}
+
+// NewReader returns a Reader decompressing the data of r
+func NewReader(r io.Reader) (*Reader, error) {
+	z, err := getReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{z: z}, nil
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.z == nil {
+		return 0, errClosed
+	}
+	n, err := r.z.Read(p)
+	r.n += int64(n)
+	return n, classify(err, r.n > 0)
+}
+
+// Close reports any error of the data read so far, and releases the
+// decoder. Further calls return the same result
+func (r *Reader) Close() error {
+	if r.z != nil {
+		r.err = classify(r.z.Close(), r.n > 0)
+		putReader(r.z)
+		r.z = nil
+	}
+	return r.err
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Errors of ReaderAt.Seek
+var (
+	errSeek   = errors.New("bz: negative position")
+	errWhence = errors.New("bz: invalid whence")
+)
+
+// A ReaderAt reads the decompressed data of a compressed file at any
+// offset, decoding only the blocks holding it, found through the
+// file's Index. The last block decoded is kept, so reads that follow
+// each other cost one decode per block. ReadAt may be called from
+// several goroutines at once; Read and Seek share a position and
+// can't.
+type ReaderAt struct {
+	f   io.ReaderAt
+	idx *Index
+	pos int64 // of Read and Seek
+
+	mu   sync.Mutex
+	last int    // block in data, -1 if none
+	data []byte // decompressed
+}
+
+// NewReaderAt returns a ReaderAt over the compressed file f, whose
+// index is idx
+func NewReaderAt(f io.ReaderAt, idx *Index) *ReaderAt {
+	return &ReaderAt{f: f, idx: idx, last: -1}
+}
+
+// Size returns the size of the decompressed data
+func (r *ReaderAt) Size() int64 {
+	return r.idx.Size
+}
+
+// block returns the decompressed data of block i
+func (r *ReaderAt) block(i int) ([]byte, error) {
+	r.mu.Lock()
+	if r.last == i {
+		data := r.data
+		r.mu.Unlock()
+		return data, nil
+	}
+	r.mu.Unlock()
+
+	b := r.idx.Blocks[i]
+	stream, err := BlockStream(r.f, b.Start, b.End, b.CRC)
+	if err != nil {
+		return nil, err
+	}
+	z, err := getReader(bytes.NewReader(stream))
+	if err != nil {
+		return nil, err
+	}
+	defer putReader(z)
+	var out bytes.Buffer
+	_, err = io.Copy(&out, z)
+	if err == nil {
+		err = z.Close()
+	}
+	if err != nil {
+		return nil, blockError(err, i+1, b.CRC, out.Bytes())
+	}
+	if int64(out.Len()) != r.idx.blockEnd(i)-b.Offset {
+		return nil, corruptError("bzip2: corrupted input: block size doesn't match the index")
+	}
+
+	r.mu.Lock()
+	r.last, r.data = i, out.Bytes()
+	r.mu.Unlock()
+	return out.Bytes(), nil
+}
+
+// ReadAt reads the decompressed data at offset off into p, returning
+// io.EOF if it ends first
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errSeek
+	}
+	n := 0
+	for n < len(p) {
+		i := r.idx.Find(off)
+		if i < 0 {
+			return n, io.EOF
+		}
+		data, err := r.block(i)
+		if err != nil {
+			return n, err
+		}
+		m := copy(p[n:], data[off-r.idx.Blocks[i].Offset:])
+		n += m
+		off += int64(m)
+	}
+	return n, nil
+}
+
+func (r *ReaderAt) Read(p []byte) (int, error) {
+	n, err := r.ReadAt(p, r.pos)
+	r.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek sets the position of Read in the decompressed data; positions
+// past the end are allowed and read as the end
+func (r *ReaderAt) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.idx.Size
+	default:
+		return 0, errWhence
+	}
+	if offset < 0 {
+		return 0, errSeek
+	}
+	r.pos = offset
+	return offset, nil
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// indexedTest returns data in several streams of several blocks, its
+// compressed form and its index
+func indexedTest(t *testing.T) (data, z []byte, idx *Index) {
+	t.Helper()
+	data = testData(350000)
+	for _, part := range [][]byte{data[:100], data[100:250000], data[250000:]} {
+		z = append(z, compressTest(t, part, &Config{Level: 1, Workers: 2, BlockSize: len(part)})...)
+	}
+	idx, err := BuildIndex(bytes.NewReader(z))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data, z, idx
+}
+
+func TestReaderAt(t *testing.T) {
+	data, z, idx := indexedTest(t)
+	r := NewReaderAt(bytes.NewReader(z), idx)
+	if r.Size() != int64(len(data)) {
+		t.Fatalf("Size = %d, want %d", r.Size(), len(data))
+	}
+	offs := []int64{0, 99, 100, 101, 100000, 249999, 349990, 350000, 360000}
+	for _, blk := range idx.Blocks {
+		offs = append(offs, blk.Offset-1, blk.Offset)
+	}
+	for _, off := range offs {
+		if off < 0 {
+			continue
+		}
+		p := make([]byte, 20)
+		n, err := r.ReadAt(p, off)
+		var want []byte
+		if off < int64(len(data)) {
+			want = data[off:]
+		}
+		if len(want) > len(p) {
+			want = want[:len(p)]
+		}
+		if !bytes.Equal(p[:n], want) || (n < len(p) && err != io.EOF) || (n == len(p) && err != nil) {
+			t.Errorf("ReadAt(%d) = %d, %v, want %d bytes", off, n, err, len(want))
+		}
+	}
+	if _, err := r.ReadAt(make([]byte, 1), -1); err == nil {
+		t.Errorf("ReadAt(-1) succeeded")
+	}
+
+	// One read over all the blocks
+	all := make([]byte, len(data))
+	if n, err := r.ReadAt(all, 0); n != len(data) || err != nil || !bytes.Equal(all, data) {
+		t.Errorf("ReadAt of everything = %d, %v", n, err)
+	}
+}
+
+// ReadAt may be called from several goroutines at once
+func TestReaderAtConcurrent(t *testing.T) {
+	data, z, idx := indexedTest(t)
+	r := NewReaderAt(bytes.NewReader(z), idx)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			p := make([]byte, 5000)
+			for i := 0; i < 20; i++ {
+				off := rnd.Int63n(int64(len(data) - len(p)))
+				if n, err := r.ReadAt(p, off); err != nil || !bytes.Equal(p[:n], data[off:off+int64(n)]) {
+					t.Errorf("ReadAt(%d) = %d, %v, or other data", off, n, err)
+					return
+				}
+			}
+		}(int64(g))
+	}
+	wg.Wait()
+}
+
+func TestReaderAtSeek(t *testing.T) {
+	data, z, idx := indexedTest(t)
+	r := NewReaderAt(bytes.NewReader(z), idx)
+	tests := []struct {
+		offset int64
+		whence int
+		want   int64
+	}{
+		{249990, io.SeekStart, 249990},
+		{-10, io.SeekCurrent, 249980},
+		{-5, io.SeekEnd, int64(len(data)) - 5},
+		{10, io.SeekEnd, int64(len(data)) + 10},
+	}
+	for _, tt := range tests {
+		pos, err := r.Seek(tt.offset, tt.whence)
+		if err != nil || pos != tt.want {
+			t.Errorf("Seek(%d, %d) = %d, %v, want %d", tt.offset, tt.whence, pos, err, tt.want)
+		}
+	}
+	if _, err := r.Seek(-1, io.SeekStart); err == nil {
+		t.Errorf("Seek to -1 succeeded")
+	}
+	if _, err := r.Seek(0, 3); err == nil {
+		t.Errorf("Seek with whence 3 succeeded")
+	}
+
+	r.Seek(249990, io.SeekStart)
+	rest, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(rest, data[249990:]) {
+		t.Errorf("read after Seek: %d bytes, %v, want %d", len(rest), err, len(data)-249990)
+	}
+}
+
+// A block that decodes to other data than indexed is caught by its CRC
+func TestReaderAtCorrupt(t *testing.T) {
+	data, z, idx := indexedTest(t)
+	bad := append([]byte(nil), z...)
+	bad[len(bad)/2] ^= 0x10
+	if _, err := NewReaderAt(bytes.NewReader(bad), idx).ReadAt(make([]byte, len(data)), 0); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("ReadAt of corrupted data: %v, want %v", err, ErrCorrupt)
+	}
+}
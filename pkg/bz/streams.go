@@ -0,0 +1,197 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Streams lists the streams concatenated in compressed data from their
+// headers, magics and footers, without decoding any block, so an input
+// of any size is listed in constant memory; the streams are found as a
+// ParallelReader finds them
+
+// StreamInfo describes a stream found by a StreamIterator
+type StreamInfo struct {
+	Offset int64  // byte offset of the header
+	Size   int64  // bytes, footer and its padding included
+	Level  int    // block size, in units of 100k
+	Blocks int    // block magics, one more per magic found by chance
+	CRC    uint32 // combined CRC stored in the footer
+}
+
+// A StreamIterator steps through the streams of compressed data, as
+// returned by Streams:
+//
+//	it := bz.Streams(r)
+//	for it.Next() {
+//		s := it.Stream()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type StreamIterator struct {
+	s   *blockScanner
+	cur StreamInfo
+	n   int // streams found
+	err error
+}
+
+// Streams returns an iterator over the streams of the compressed data
+// of r
+func Streams(r io.Reader) *StreamIterator {
+	return &StreamIterator{s: &blockScanner{r: r}}
+}
+
+// Next advances to the next stream, reporting false at the end of
+// the data or on an error, which Err then returns
+func (it *StreamIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	pos := it.cur.Offset + it.cur.Size
+	it.cur, it.err = it.s.stream(pos, nil)
+	if it.err == io.EOF && it.n == 0 {
+		it.err = io.ErrUnexpectedEOF
+	}
+	if it.err != nil {
+		it.cur = StreamInfo{Offset: pos}
+		return false
+	}
+	it.n++
+	return true
+}
+
+// Stream returns the stream Next advanced to
+func (it *StreamIterator) Stream() StreamInfo {
+	return it.cur
+}
+
+// Err returns the error that ended the iteration; nil at the end of
+// the data. Data after the last stream that isn't another one is
+// ErrTrailingGarbage
+func (it *StreamIterator) Err() error {
+	if it.err == io.EOF {
+		return nil
+	}
+	return it.err
+}
+
+// isHeader tells whether hdr is a stream header
+func isHeader(hdr []byte) bool {
+	return bytes.HasPrefix(hdr, streamMagic) && hdr[3] >= '1' && hdr[3] <= '9'
+}
+
+// stream scans the stream whose header is at byte pos, handing each
+// candidate [start, end) to block, if set; that of the footer has
+// last set and the stream's combined CRC. A footer magic ends the
+// stream only when the end of the data, another header or no other
+// magic for maxBlockBytes follows it. It returns io.EOF if the data
+// ends at pos
+func (s *blockScanner) stream(pos int64, block func(start, end int64, last bool, streamCRC uint32) error) (StreamInfo, error) {
+	st := StreamInfo{Offset: pos}
+	candidate := func(start, end int64, last bool, streamCRC uint32) error {
+		st.Blocks++
+		if block != nil {
+			if err := block(start, end, last, streamCRC); err != nil {
+				return err
+			}
+		}
+		s.discard(end / 8)
+		return nil
+	}
+
+	hdr := make([]byte, 4)
+	n, err := s.ReadAt(hdr, pos)
+	if n == 0 && err == io.EOF {
+		return st, io.EOF
+	}
+	if n < len(hdr) {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return st, err
+	}
+	if !isHeader(hdr) {
+		if pos > 0 {
+			return st, ErrTrailingGarbage
+		}
+		return st, ErrNotBzip2
+	}
+	st.Level = int(hdr[3] - '0')
+
+	// The first magic must follow the header immediately
+	want := (pos + 4) * 8
+	m, ok, err := s.nextMark(want, -1)
+	if err != nil {
+		return st, err
+	}
+	if !ok {
+		return st, io.ErrUnexpectedEOF
+	}
+	if m.Bit != want {
+		return st, corruptError("bzip2: corrupted input: invalid block magic")
+	}
+	start := int64(-1) // of the current candidate, none before the first block
+	for {
+		if !m.End {
+			if start >= 0 {
+				if err := candidate(start, m.Bit, false, 0); err != nil {
+					return st, err
+				}
+			}
+			start = m.Bit
+			if m, ok, err = s.nextMark(start+48, -1); err != nil {
+				return st, err
+			} else if !ok {
+				return st, io.ErrUnexpectedEOF
+			}
+			continue
+		}
+
+		// Footer: magic, combined CRC, padding to a byte boundary
+		end := (m.Bit + 80 + 7) / 8
+		crcBits, err := ReadBits(s, m.Bit+48, m.Bit+80)
+		if err != nil {
+			return st, err
+		}
+		st.CRC = binary.BigEndian.Uint32(crcBits)
+		st.Size = end - pos
+		if start < 0 {
+			if st.CRC != 0 {
+				return st, &ErrCRCMismatch{Stream: true, Want: st.CRC}
+			}
+			return st, nil
+		}
+		n, err := s.ReadAt(hdr, end)
+		ends := n == 0 || n == len(hdr) && isHeader(hdr)
+		if err != nil && err != io.EOF {
+			return st, err
+		}
+		var next Mark
+		if !ends {
+			// Garbage follows unless another magic comes soon
+			next, ok, err = s.nextMark(m.Bit+48, m.Bit+48+maxBlockBytes*8)
+			if err != nil {
+				return st, err
+			}
+			ends = !ok
+		}
+		if ends {
+			return st, candidate(start, m.Bit, true, st.CRC)
+		}
+		if s.trace != nil {
+			s.trace(TraceDebug, "    decode: footer magic at bit %d inside a block\n", m.Bit)
+		}
+		if err := candidate(start, m.Bit, false, 0); err != nil {
+			return st, err
+		}
+		start, m = m.Bit, next
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestStreams(t *testing.T) {
+	parts := [][]byte{[]byte("hello, world\n"), nil, testData(90000), testData(5000)}
+	levels := []int{9, 5, 1, 1}
+	var z []byte
+	var offs []int64
+	for i, part := range parts {
+		offs = append(offs, int64(len(z)))
+		z = append(z, compressTest(t, part, &Config{Level: levels[i], BlockSize: len(part) + 1})...)
+	}
+	it := Streams(bytes.NewReader(z))
+	n := 0
+	for it.Next() {
+		s := it.Stream()
+		if n == len(parts) {
+			t.Fatalf("stream %d found past the last", n)
+		}
+		end := int64(len(z))
+		if n+1 < len(offs) {
+			end = offs[n+1]
+		}
+		combined := uint32(0)
+		blocks := 0
+		if len(parts[n]) > 0 {
+			combined, blocks = UpdateCRC(0, parts[n]), 1
+		}
+		if s.Offset != offs[n] || s.Offset+s.Size != end || s.Level != levels[n] || s.Blocks != blocks || s.CRC != combined {
+			t.Errorf("stream %d: %+v, want offset %d, size %d, level %d, %d blocks, CRC 0x%08x",
+				n, s, offs[n], end-offs[n], levels[n], blocks, combined)
+		}
+		n++
+	}
+	if err := it.Err(); err != nil || n != len(parts) {
+		t.Errorf("%d streams, %v, want %d", n, err, len(parts))
+	}
+}
+
+func TestStreamsErrors(t *testing.T) {
+	z := compressTest(t, []byte("hello, world\n"), nil)
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty", nil, io.ErrUnexpectedEOF},
+		{"not bzip2", []byte("hello, world\n"), ErrNotBzip2},
+		{"trailing garbage", append(append([]byte(nil), z...), "garbage"...), ErrTrailingGarbage},
+	}
+	for _, tt := range tests {
+		it := Streams(bytes.NewReader(tt.data))
+		for it.Next() {
+		}
+		if err := it.Err(); !errors.Is(err, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2025: Pindorama
+// All rights reserved.
+// Use of this source code is governed by a ISC license that
+// can be found in the LICENSE file.
+
+package bz
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	data := testData(250000)
+	z := compressTest(t, data, &Config{Level: 1, Workers: 2, Verify: true})
+	if got := decompressSerial(t, z); !bytes.Equal(got, data) {
+		t.Errorf("verified compression decoded to %d bytes, want %d", len(got), len(data))
+	}
+}
+
+// A block whose stream doesn't decode to its input fails to verify
+func TestVerifyBlock(t *testing.T) {
+	data := testData(50000)
+	tests := []struct {
+		name   string
+		damage func(b *block)
+	}{
+		{"changed input", func(b *block) { b.in[100] ^= 1 }},
+		{"longer input", func(b *block) { b.in = append(b.in, 'x') }},
+		{"shorter input", func(b *block) { b.in = b.in[:len(b.in)-1] }},
+		{"truncated stream", func(b *block) { b.out.Truncate(b.out.Len() - 4) }},
+		{"damaged stream", func(b *block) { b.out.Bytes()[b.out.Len()/2] ^= 0x40 }},
+	}
+	for _, tt := range tests {
+		zr, _ := getReader(nil)
+		b := &block{in: append([]byte(nil), data...)}
+		if err := compressBlock(b, 1, zr); err != nil {
+			t.Fatalf("%s: sound block: %v", tt.name, err)
+		}
+		tt.damage(b)
+		if err := verifyBlock(zr, b); !errors.Is(err, ErrVerify) {
+			t.Errorf("%s: %v, want %v", tt.name, err, ErrVerify)
+		}
+		putReader(zr)
+	}
+}